@@ -3,11 +3,13 @@ package goloquent
 import (
 	"bytes"
 	"context"
-	"crypto/sha1"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -66,6 +68,37 @@ func (b *builder) addIndex(ctx context.Context, fields []string, idx index) erro
 	})
 }
 
+func (b *builder) dropColumn(ctx context.Context, cols []string) error {
+	for _, col := range cols {
+		buf := new(bytes.Buffer)
+		buf.WriteString(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;",
+			b.db.dialect.GetTable(b.query.table),
+			b.db.dialect.Quote(col)))
+		if err := b.db.client.execStmt(ctx, &stmt{
+			statement: buf,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *builder) dropIndex(ctx context.Context, idxs []string) error {
+	for _, idx := range idxs {
+		if !b.db.dialect.HasIndex(ctx, b.query.table, idx) {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		buf.WriteString(b.db.dialect.DropIndex(b.query.table, idx))
+		if err := b.db.client.execStmt(ctx, &stmt{
+			statement: buf,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *builder) dropTableIfExists(ctx context.Context, table string) error {
 	buf := new(bytes.Buffer)
 	buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;", b.db.dialect.GetTable(table)))
@@ -75,14 +108,99 @@ func (b *builder) dropTableIfExists(ctx context.Context, table string) error {
 }
 
 func (b *builder) quoteIfNecessary(v string) string {
-	if regexp.MustCompile("^[a-zA-Z\\d]+(\\.[a-zA-Z\\d]+)*$").MatchString(v) {
-		return b.db.dialect.Quote(v)
+	if regexp.MustCompile("^\\$?[a-zA-Z\\d]+(\\.\\$?[a-zA-Z\\d]+)*$").MatchString(v) {
+		return b.quoteColumn(b.splitColumn(v))
 	}
 	return v
 }
 
+// tableAlias : name to refer the query table
+func (b *builder) tableAlias() string {
+	if b.query.alias != "" {
+		return b.query.alias
+	}
+	return b.query.table
+}
+
+// aliases : table of each alias (or table name if there is no alias)
+func (b *builder) aliases() map[string]string {
+	m := map[string]string{b.tableAlias(): b.query.table}
+	for _, j := range b.query.joins {
+		if j.alias != "" {
+			m[j.alias] = j.table
+			continue
+		}
+		m[j.table] = j.table
+	}
+	return m
+}
+
+// splitColumn : split the column which qualified by table alias, such as "m.Name",
+// the reserved column will be qualified by the query table when there is join
+func (b *builder) splitColumn(name string) (alias string, col string) {
+	if len(b.query.joins) <= 0 {
+		return "", name
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		if _, isOk := b.aliases()[name[:i]]; isOk {
+			return name[:i], name[i+1:]
+		}
+	}
+	switch name {
+	case keyFieldName, pkColumn, parentColumn, softDeleteColumn:
+		return b.tableAlias(), name
+	}
+	return "", name
+}
+
+func (b *builder) quoteColumn(alias, col string) string {
+	if alias == "" {
+		return b.db.dialect.Quote(col)
+	}
+	return b.db.dialect.Quote(alias) + "." + b.db.dialect.Quote(col)
+}
+
+// columnExpr : expression of the column, `__key__` is the full key and `$Parent` is the parent key
+func (b *builder) columnExpr(name string) string {
+	alias, col := b.splitColumn(name)
+	switch col {
+	case keyFieldName:
+		table := b.query.table
+		if alias != "" {
+			table = b.aliases()[alias]
+		}
+		return b.db.dialect.KeyExpr(b.quoteColumn(alias, pkColumn), table)
+	case parentColumn:
+		return b.db.dialect.ParentKeyExpr(b.quoteColumn(alias, pkColumn))
+	}
+	return b.quoteColumn(alias, col)
+}
+
+func (b *builder) buildFrom() string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(" FROM " + b.db.dialect.GetTable(b.query.table))
+	if b.query.alias != "" {
+		buf.WriteString(" AS " + b.db.dialect.Quote(b.query.alias))
+	}
+	for _, j := range b.query.joins {
+		buf.WriteString(" " + j.kind + " " + b.db.dialect.GetTable(j.table))
+		if j.alias != "" {
+			buf.WriteString(" AS " + b.db.dialect.Quote(j.alias))
+		}
+		op := j.op
+		if op == "!=" {
+			op = "<>"
+		}
+		buf.WriteString(fmt.Sprintf(" ON %s %s %s", b.columnExpr(j.left), op, b.columnExpr(j.right)))
+	}
+	return buf.String()
+}
+
 func (b *builder) buildSelect(query scope) *stmt {
 	scope := "*"
+	if len(query.joins) > 0 {
+		scope = b.db.dialect.Quote(b.tableAlias()) + ".*"
+	}
 	if len(query.projection) > 0 {
 		projection := make([]string, len(query.projection), len(query.projection))
 		copy(projection, query.projection)
@@ -91,6 +209,20 @@ func (b *builder) buildSelect(query scope) *stmt {
 		}
 		scope = strings.Join(projection, ",")
 	}
+	if len(query.aggregates) > 0 {
+		projection := make([]string, 0, len(query.projection)+len(query.aggregates))
+		for _, p := range query.projection {
+			projection = append(projection, b.quoteIfNecessary(p))
+		}
+		for _, a := range query.aggregates {
+			str := b.buildAggregate(a)
+			if a.Alias != "" {
+				str += " AS " + b.db.dialect.Quote(a.Alias)
+			}
+			projection = append(projection, str)
+		}
+		scope = strings.Join(projection, ",")
+	}
 	if len(query.distinctOn) > 0 {
 		distinctOn := make([]string, len(query.distinctOn), len(query.distinctOn))
 		copy(distinctOn, query.distinctOn)
@@ -109,11 +241,114 @@ func (b *builder) buildSelect(query scope) *stmt {
 
 func (b *builder) buildWhere(query scope) (*stmt, error) {
 	buf := new(bytes.Buffer)
+	wheres, args, err := b.buildFilters(query.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, aa := range query.ancestors {
+		if aa.isGroup {
+			buf := new(bytes.Buffer)
+			buf.WriteByte('(')
+			for _, x := range aa.data {
+				buf.WriteString(fmt.Sprintf("%s LIKE %s OR ", b.quoteColumn(b.splitColumn(pkColumn)), variable))
+				args = append(args, fmt.Sprintf("%%%s/%%", stringifyKey(x.(*datastore.Key))))
+			}
+			buf.Truncate(buf.Len() - 4)
+			buf.WriteByte(')')
+			wheres = append(wheres, buf.String())
+			continue
+		}
+
+		wheres = append(wheres, b.quoteColumn(b.splitColumn(pkColumn))+" LIKE "+variable)
+		args = append(args, fmt.Sprintf("%%%s/%%", stringifyKey(aa.data[0].(*datastore.Key))))
+	}
+
+	if len(wheres) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wheres, " AND "))
+	} else {
+		buf.Reset()
+	}
+
+	return &stmt{
+		statement: buf,
+		arguments: args,
+	}, nil
+}
+
+func (b *builder) buildAggregate(a expr.Aggregate) string {
+	name := a.Name
+	switch alias, col := b.splitColumn(name); col {
+	case "*":
+	case keyFieldName, pkColumn:
+		name = b.quoteColumn(alias, pkColumn)
+	default:
+		name = b.quoteColumn(alias, col)
+	}
+	return fmt.Sprintf("%s(%s)", strings.ToUpper(a.Func), name)
+}
+
+func (b *builder) buildGroupBy(query scope) (*stmt, error) {
+	buf := new(bytes.Buffer)
+	if len(query.groupBy) <= 0 {
+		return &stmt{statement: buf}, nil
+	}
+	fields := make([]string, 0, len(query.groupBy))
+	for _, f := range query.groupBy {
+		alias, col := b.splitColumn(f)
+		if col == keyFieldName {
+			col = pkColumn
+		}
+		fields = append(fields, b.quoteColumn(alias, col))
+	}
+	buf.WriteString(" GROUP BY " + strings.Join(fields, ","))
+	havings, args, err := b.buildFilters(query.havings)
+	if err != nil {
+		return nil, err
+	}
+	if len(havings) > 0 {
+		buf.WriteString(" HAVING " + strings.Join(havings, " AND "))
+	}
+	return &stmt{
+		statement: buf,
+		arguments: args,
+	}, nil
+}
+
+func (b *builder) buildFilters(filters []Filter) ([]string, []interface{}, error) {
 	wheres := make([]string, 0)
 	args := make([]interface{}, 0)
 
-	for _, f := range query.filters {
-		name := b.db.dialect.Quote(f.Field())
+	for _, f := range filters {
+		if len(f.group) > 0 {
+			conds, vv, err := b.buildFilters(f.group)
+			if err != nil {
+				return nil, nil, err
+			}
+			buf := new(bytes.Buffer)
+			buf.WriteByte('(')
+			for i, c := range conds {
+				if i > 0 {
+					if f.group[i].isOr {
+						buf.WriteString(" OR ")
+					} else {
+						buf.WriteString(" AND ")
+					}
+				}
+				buf.WriteString(c)
+			}
+			buf.WriteByte(')')
+			wheres = append(wheres, buf.String())
+			args = append(args, vv...)
+			continue
+		}
+
+		alias, col := b.splitColumn(f.Field())
+		name := b.quoteColumn(alias, col)
+		if f.aggregate != nil {
+			name = b.buildAggregate(*f.aggregate)
+		}
 
 		var v interface{}
 		switch vi := f.value.(type) {
@@ -125,7 +360,7 @@ func (b *builder) buildWhere(query scope) (*stmt, error) {
 			subQuery.WriteString(b.db.dialect.GetTable(vi.scope.table))
 			stmt, err := b.buildStmt(vi.scope)
 			if err != nil {
-				return nil, fmt.Errorf("goloquent: %v", err)
+				return nil, nil, fmt.Errorf("goloquent: %w", err)
 			}
 			subQuery.WriteString(stmt.string())
 			subQuery.WriteString(")")
@@ -135,25 +370,30 @@ func (b *builder) buildWhere(query scope) (*stmt, error) {
 		default:
 			vi, err := f.Interface()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			if f.IsJSON() {
 				str, vv, err := b.db.dialect.FilterJSON(f)
 				if err != nil {
-					return nil, fmt.Errorf("goloquent: %w", err)
+					return nil, nil, fmt.Errorf("goloquent: %w", err)
 				}
 				wheres = append(wheres, str)
 				args = append(args, vv...)
 				continue
 			}
 
-			switch f.Field() {
+			switch col {
 			case keyFieldName, pkColumn:
-				name = b.db.dialect.Quote(pkColumn)
+				name = b.quoteColumn(alias, pkColumn)
 				vi, err = interfaceToKeyString(f.value)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
+				}
+			case parentColumn:
+				name = b.columnExpr(f.Field())
+				if k, isOk := f.value.(*datastore.Key); isOk {
+					vi = stringifyKey(k)
 				}
 			}
 			v = vi
@@ -188,7 +428,7 @@ func (b *builder) buildWhere(query scope) (*stmt, error) {
 				x = append(x, v)
 			}
 			if len(x) <= 0 {
-				return nil, fmt.Errorf(`goloquent: value for "AnyLike" operator cannot be empty`)
+				return nil, nil, fmt.Errorf(`goloquent: value for "AnyLike" operator cannot be empty`)
 			}
 			buf := new(bytes.Buffer)
 			buf.WriteByte('(')
@@ -214,7 +454,7 @@ func (b *builder) buildWhere(query scope) (*stmt, error) {
 					x = append(x, v)
 				}
 				if len(x) <= 0 {
-					return nil, fmt.Errorf(`goloquent: value for "In" operator cannot be empty`)
+					return nil, nil, fmt.Errorf(`goloquent: value for "In" operator cannot be empty`)
 				}
 				vv = fmt.Sprintf("(%s)", strings.TrimRight(
 					strings.Repeat(variable+",", len(x)), ","))
@@ -235,7 +475,7 @@ func (b *builder) buildWhere(query scope) (*stmt, error) {
 					x = append(x, v)
 				}
 				if len(x) <= 0 {
-					return nil, fmt.Errorf(`goloquent: value for "NotIn" operator cannot be empty`)
+					return nil, nil, fmt.Errorf(`goloquent: value for "NotIn" operator cannot be empty`)
 				}
 				vv = fmt.Sprintf("(%s)", strings.TrimRight(
 					strings.Repeat(variable+",", len(x)), ","))
@@ -266,35 +506,7 @@ func (b *builder) buildWhere(query scope) (*stmt, error) {
 		args = append(args, v)
 	}
 
-	for _, aa := range query.ancestors {
-		if aa.isGroup {
-			buf := new(bytes.Buffer)
-			buf.WriteByte('(')
-			for _, x := range aa.data {
-				buf.WriteString(fmt.Sprintf("%s LIKE %s OR ", b.db.dialect.Quote(pkColumn), variable))
-				args = append(args, fmt.Sprintf("%%%s/%%", stringifyKey(x.(*datastore.Key))))
-			}
-			buf.Truncate(buf.Len() - 4)
-			buf.WriteByte(')')
-			wheres = append(wheres, buf.String())
-			continue
-		}
-
-		wheres = append(wheres, b.db.dialect.Quote(pkColumn)+" LIKE "+variable)
-		args = append(args, fmt.Sprintf("%%%s/%%", stringifyKey(aa.data[0].(*datastore.Key))))
-	}
-
-	if len(wheres) > 0 {
-		buf.WriteString(" WHERE ")
-		buf.WriteString(strings.Join(wheres, " AND "))
-	} else {
-		buf.Reset()
-	}
-
-	return &stmt{
-		statement: buf,
-		arguments: args,
-	}, nil
+	return wheres, args, nil
 }
 
 func (b *builder) buildOrderBy(query scope) (*stmt, error) {
@@ -309,6 +521,13 @@ func (b *builder) buildOrderBy(query scope) (*stmt, error) {
 			if i > 0 {
 				buf.WriteByte(',')
 			}
+			if x, isOk := o.(expr.Sort); isOk {
+				buf.WriteString(b.quoteColumn(b.splitColumn(x.Name)))
+				if x.Direction == expr.Descending {
+					buf.WriteString(" DESC")
+				}
+				continue
+			}
 			vals, err := stmtRegistry.BuildStatement(buf, reflect.ValueOf(o))
 			if err != nil {
 				return nil, err
@@ -346,6 +565,12 @@ func (b *builder) buildStmt(query scope, args ...interface{}) (*stmt, error) {
 		args = append(args, cmd.arguments...)
 		buf.WriteString(cmd.string())
 	}
+	gs, err := b.buildGroupBy(query)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString(gs.string())
+	args = append(args, gs.arguments...)
 	ss, err := b.buildOrderBy(query)
 	if err != nil {
 		return nil, err
@@ -364,7 +589,7 @@ func (b *builder) createTable(ctx context.Context, e *entity) error {
 }
 
 func (b *builder) alterTable(ctx context.Context, e *entity) error {
-	return b.db.dialect.AlterTable(ctx, e.Name(), e.columns, false)
+	return b.db.dialect.AlterTable(ctx, e.Name(), e.columns, b.db.unsafe)
 }
 
 func (b *builder) migrate(ctx context.Context, model interface{}) error {
@@ -379,6 +604,30 @@ func (b *builder) migrate(ctx context.Context, model interface{}) error {
 	return b.createTable(ctx, e)
 }
 
+func (b *builder) migratePlan(ctx context.Context, model interface{}) (*TablePlan, error) {
+	e, err := newEntity(model)
+	if err != nil {
+		return nil, err
+	}
+	e.setName(b.query.table)
+	if b.db.dialect.HasTable(ctx, e.Name()) {
+		return b.db.dialect.PlanAlterTable(ctx, e.Name(), e.columns, b.db.unsafe)
+	}
+	return b.db.dialect.PlanCreateTable(ctx, e.Name(), e.columns)
+}
+
+func (b *builder) migratePlanMultiple(ctx context.Context, models []interface{}) (*MigrationPlan, error) {
+	plan := new(MigrationPlan)
+	for _, m := range models {
+		p, err := b.migratePlan(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		plan.Tables = append(plan.Tables, *p)
+	}
+	return plan, nil
+}
+
 func (b *builder) migrateMultiple(ctx context.Context, models []interface{}) error {
 	for _, m := range models {
 		if err := b.migrate(ctx, m); err != nil {
@@ -389,28 +638,18 @@ func (b *builder) migrateMultiple(ctx context.Context, models []interface{}) err
 }
 
 func (b *builder) getCommand(e *entity) (*stmt, error) {
+	b.query.table = e.Name()
 	query := b.query
 	buf := new(bytes.Buffer)
 	buf.WriteString(b.buildSelect(query).string())
-	buf.WriteString(" FROM " + b.db.dialect.GetTable(e.Name()))
-	if !query.noScope && e.hasSoftDelete() {
-		query.filters = append(query.filters, Filter{
-			field:    softDeleteColumn,
-			operator: Equal,
-			value:    nil,
-		})
-	}
+	buf.WriteString(b.buildFrom())
+	query = query.softDeleteScope(e.hasSoftDelete())
 	cmd, err := b.buildStmt(query)
 	if err != nil {
 		return nil, err
 	}
 	buf.WriteString(cmd.string())
-	switch query.lockMode {
-	case ReadLock:
-		buf.WriteString(" LOCK IN SHARE MODE")
-	case WriteLock:
-		buf.WriteString(" FOR UPDATE")
-	}
+	buf.WriteString(b.db.dialect.LockMode(query.lockMode))
 	buf.WriteString(";")
 
 	return &stmt{
@@ -422,42 +661,79 @@ func (b *builder) getCommand(e *entity) (*stmt, error) {
 func (b *builder) run(ctx context.Context, table string, cmd *stmt) (*Iterator, error) {
 	var rows, err = b.db.client.execQuery(ctx, cmd)
 	if err != nil {
-		return nil, fmt.Errorf("goloquent: %v", err)
+		return nil, fmt.Errorf("goloquent: %w", err)
 	}
 	defer rows.Close()
 	cols, err := rows.Columns()
 	if err != nil {
-		return nil, fmt.Errorf("goloquent: %v", err)
+		return nil, fmt.Errorf("goloquent: %w", err)
+	}
+
+	sign, err := b.querySign(table)
+	if err != nil {
+		return nil, err
 	}
 
 	it := Iterator{
+		db:       b.db,
 		table:    table,
 		stmt:     &Stmt{stmt: *cmd, replacer: b.db.dialect},
+		sign:     sign,
+		secret:   b.db.cursorSecret(),
 		position: -1,
 		columns:  cols,
+		orders:   b.sortNames(b.query.orders),
 	}
 
-	i := 0
 	for rows.Next() {
-		m := make([]interface{}, len(cols))
-		for j := range cols {
-			m[j] = &m[j]
-		}
-
-		if err := rows.Scan(m...); err != nil {
+		if err := it.scanRow(rows); err != nil {
 			return nil, err
 		}
-
-		for j, name := range cols {
-			it.put(i, name, m[j])
-		}
-		it.patchKey()
-		i++
 	}
 
 	return &it, nil
 }
 
+func (b *builder) iter(ctx context.Context, model interface{}) (*StreamIterator, error) {
+	e, err := newEntity(model)
+	if err != nil {
+		return nil, err
+	}
+	e.setName(b.query.table)
+	cmd, err := b.getCommand(e)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := b.db.client.execQuery(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("goloquent: %w", err)
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("goloquent: %w", err)
+	}
+	sign, err := b.querySign(e.Name())
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+
+	return &StreamIterator{
+		rows: rows,
+		it: Iterator{
+			db:      b.db,
+			table:   e.Name(),
+			stmt:    &Stmt{stmt: *cmd, replacer: b.db.dialect},
+			sign:    sign,
+			secret:  b.db.cursorSecret(),
+			columns: cols,
+			orders:  b.sortNames(b.query.orders),
+		},
+	}, nil
+}
+
 func (b *builder) get(ctx context.Context, model interface{}, mustExist bool) error {
 	e, err := newEntity(model)
 	if err != nil {
@@ -484,6 +760,10 @@ func (b *builder) get(ctx context.Context, model interface{}, mustExist bool) er
 		if err != nil {
 			return err
 		}
+		v := reflect.ValueOf(model)
+		if err := b.loadWith(ctx, reflect.Append(reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1), v)); err != nil {
+			return err
+		}
 	} else {
 		v := reflect.ValueOf(model)
 		vi := reflect.New(v.Type().Elem())
@@ -522,111 +802,75 @@ func (b *builder) getMulti(ctx context.Context, model interface{}) error {
 		}
 		vv = reflect.Append(vv, vi)
 	}
+	if err := b.loadWith(ctx, vv); err != nil {
+		return err
+	}
 	v.Set(vv)
 	return nil
 }
 
-func baseToInterface(it interface{}) interface{} {
-	var v interface{}
-	switch vi := it.(type) {
-	case nil, bool, uint64, int64, float64, string:
-		v = vi
-	case []byte:
-		v = string(vi)
-	case time.Time:
-		v = vi.Format("2006-01-02 15:04:05")
-	default:
-		v = vi
-	}
-	return v
-}
-
 func (b *builder) paginate(ctx context.Context, p *Pagination, model interface{}) error {
 	e, err := newEntity(model)
 	if err != nil {
 		return err
 	}
 	e.setName(b.query.table)
+	for _, o := range b.query.orders {
+		if _, isOk := o.(expr.Sort); !isOk {
+			return errors.New("goloquent: paginate only support string order")
+		}
+	}
 	cmds, err := b.getCommand(e)
 	if err != nil {
 		return err
 	}
 
-	oriCmd := *cmds
+	c := Cursor{}
 	if p.Cursor != "" {
-		c, err := DecodeCursor(p.Cursor)
+		c, err = DecodeCursor(p.Cursor)
+		if err != nil {
+			return err
+		}
+		sign, err := b.querySign(e.Name())
 		if err != nil {
 			return err
 		}
-		// if sha1Sign(&Stmt{stmt: *cmds, replacer: b.db.dialect}) != c.Signature {
-		// 	return ErrInvalidCursor
-		// }
+		if !c.verify(b.db.cursorSecrets) || c.Signature != sign {
+			return ErrInvalidCursor
+		}
+		if len(b.db.cursorSecrets) <= 0 {
+			unsignedCursorWarning.Do(func() {
+				log.Println("goloquent: the pagination cursor is unsigned and can be forged, use `SetCursorSecret` to sign the cursor")
+			})
+		}
 		query := b.query
+		// walking backward by inverting the sort direction, the result will be reversed later
+		if c.Backward {
+			query.orders = invertOrders(query.orders)
+		}
+		if len(c.Values) != len(query.orders) {
+			return ErrInvalidCursor
+		}
 		buf, args := new(bytes.Buffer), make([]interface{}, 0)
 		buf.WriteString(b.buildSelect(query).string())
-		buf.WriteString(fmt.Sprintf(" FROM %s", b.db.dialect.GetTable(e.Name())))
-		if !query.noScope && e.hasSoftDelete() {
-			query.filters = append(query.filters, Filter{
-				field:    softDeleteColumn,
-				operator: Equal,
-				value:    nil,
-			})
-		}
+		buf.WriteString(b.buildFrom())
+		query = query.softDeleteScope(e.hasSoftDelete())
 		cmd, err := b.buildWhere(query)
 		if err != nil {
 			return err
 		}
-
-		orders := query.orders
-		projection := make([]string, 0, len(orders))
-		for _, o := range orders {
-			x, isOk := o.(expr.Sort)
-			if !isOk {
-				return errors.New("paginate only support string order")
-			}
-			projection = append(projection, x.Name)
-		}
-		values, or := make([]interface{}, len(orders)), make([]string, 0)
-		for i := 0; i < len(values); i++ {
-			values[i] = &values[i]
-		}
 		if !cmd.isZero() {
 			args = append(args, cmd.arguments...)
 			buf.WriteString(cmd.string() + " AND ")
 		} else {
-			if len(orders) > 0 {
-				buf.WriteString(" WHERE ")
-			}
+			buf.WriteString(" WHERE ")
 		}
-		if err := b.db.Table(e.Name()).
-			WhereEqual(keyFieldName, c.Key).
-			Select(projection...).
-			Limit(1).Scan(ctx, values...); err != nil {
-			return ErrInvalidCursor
+		keyset, err := b.buildKeyset(query.orders, c.Values)
+		if err != nil {
+			return err
 		}
-		arg := make([]interface{}, 0, len(orders))
-		for i, o := range orders {
-			x, isOk := o.(expr.Sort)
-			if !isOk {
-				return errors.New("paginate only support string order")
-			}
-			vv := baseToInterface(values[i])
-			op := ">="
-			if x.Direction == expr.Descending {
-				op = "<="
-			}
-			if i < len(orders)-1 {
-				buf.WriteString(fmt.Sprintf("%s %s %s AND ",
-					b.db.dialect.Quote(x.Name), op, variable))
-				args = append(args, vv)
-				op = strings.Trim(op, "=")
-			}
-			or = append(or, fmt.Sprintf("%s %s %s",
-				b.db.dialect.Quote(x.Name), op, variable))
-			arg = append(arg, vv)
-		}
-		buf.WriteString("(" + strings.Join(or, " OR ") + ")")
-		args = append(args, arg...)
+		buf.WriteString(keyset.string())
+		args = append(args, keyset.arguments...)
 		ss, err := b.buildOrderBy(query)
 		if err != nil {
 			return err
@@ -634,6 +878,7 @@ func (b *builder) paginate(ctx context.Context, p *Pagination, model interface{}
 		buf.WriteString(ss.string())
 		args = append(args, ss.arguments...)
 		buf.WriteString(b.buildLimitOffset(query).string())
+		buf.WriteString(b.db.dialect.LockMode(query.lockMode))
 		buf.WriteString(";")
 		cmds = &stmt{statement: buf, arguments: args}
 	}
@@ -643,7 +888,6 @@ func (b *builder) paginate(ctx context.Context, p *Pagination, model interface{}
 		return err
 	}
 
-	it.stmt = &Stmt{stmt: oriCmd, replacer: b.db.dialect}
 	i, v := uint(1), reflect.Indirect(reflect.ValueOf(model))
 	vv := reflect.MakeSlice(v.Type(), 0, 0)
 	isPtr, t := checkMultiPtr(v)
@@ -656,8 +900,6 @@ func (b *builder) paginate(ctx context.Context, p *Pagination, model interface{}
 		if err != nil {
 			return err
 		}
-		cc, _ := it.Cursor()
-		p.nxtCursor = cc
 		if !isPtr {
 			vi = vi.Elem()
 		}
@@ -665,17 +907,148 @@ func (b *builder) paginate(ctx context.Context, p *Pagination, model interface{}
 		i++
 	}
 
-	v.Set(vv)
 	count := it.Count()
-	if count <= p.Limit {
-		p.nxtCursor = Cursor{}
+	p.nxtCursor, p.prvCursor = Cursor{}, Cursor{}
+	last := int(count) - 1
+	if count > p.Limit {
+		last = int(p.Limit) - 1
+	}
+	if c.Backward {
+		reverseSlice(vv)
+		// the extra record indicate there still have previous record set
+		if count > p.Limit {
+			p.prvCursor, err = it.cursorAt(last, true)
+			if err != nil {
+				return err
+			}
+		}
+		if count > 0 {
+			p.nxtCursor, err = it.cursorAt(0, false)
+			if err != nil {
+				return err
+			}
+		}
 	} else {
-		count--
+		if count > p.Limit {
+			p.nxtCursor, err = it.cursorAt(last, false)
+			if err != nil {
+				return err
+			}
+		}
+		if c.Key != nil && count > 0 {
+			p.prvCursor, err = it.cursorAt(0, true)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := b.loadWith(ctx, vv); err != nil {
+		return err
+	}
+	v.Set(vv)
+	if count > p.Limit {
+		count = p.Limit
 	}
 	p.count = count
 	return nil
 }
 
+// buildKeyset : the record set strictly after the cursor values, using the expanded disjunction
+// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND $Key > ?), it respect the NULL ordering of the dialect
+func (b *builder) buildKeyset(orders []interface{}, values []interface{}) (*stmt, error) {
+	ors, args := make([]string, 0, len(orders)), make([]interface{}, 0)
+	eqs, eqArgs := make([]string, 0, len(orders)), make([]interface{}, 0)
+	for i, o := range orders {
+		x, isOk := o.(expr.Sort)
+		if !isOk {
+			return nil, errors.New("goloquent: paginate only support string order")
+		}
+		alias, col := b.splitColumn(x.Name)
+		name, v := b.quoteColumn(alias, col), values[i]
+		desc := x.Direction == expr.Descending
+		nullsFirst := b.db.dialect.NullsFirst() != desc
+
+		after, afterArgs := "", make([]interface{}, 0)
+		switch {
+		case v == nil && nullsFirst:
+			after = name + " IS NOT NULL"
+		case v == nil:
+			// NULL is the last, nothing is after it
+		default:
+			op := ">"
+			if desc {
+				op = "<"
+			}
+			after = fmt.Sprintf("%s %s %s", name, op, variable)
+			afterArgs = append(afterArgs, v)
+			if !nullsFirst && col != pkColumn {
+				after = fmt.Sprintf("(%s OR %s IS NULL)", after, name)
+			}
+		}
+		if after != "" {
+			conds := append(append(make([]string, 0, len(eqs)+1), eqs...), after)
+			ors = append(ors, "("+strings.Join(conds, " AND ")+")")
+			args = append(args, eqArgs...)
+			args = append(args, afterArgs...)
+		}
+
+		if v == nil {
+			eqs = append(eqs, name+" IS NULL")
+		} else {
+			eqs = append(eqs, name+" = "+variable)
+			eqArgs = append(eqArgs, v)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if len(ors) <= 0 {
+		buf.WriteString("1 = 0")
+	} else {
+		buf.WriteString("(" + strings.Join(ors, " OR ") + ")")
+	}
+	return &stmt{
+		statement: buf,
+		arguments: args,
+	}, nil
+}
+
+// sortNames : the sort columns without the table alias, which are the columns of the result
+func (b *builder) sortNames(orders []interface{}) []string {
+	names := make([]string, 0, len(orders))
+	for _, o := range orders {
+		if x, isOk := o.(expr.Sort); isOk {
+			_, col := b.splitColumn(x.Name)
+			names = append(names, col)
+		}
+	}
+	return names
+}
+
+func invertOrders(orders []interface{}) []interface{} {
+	arr := make([]interface{}, 0, len(orders))
+	for _, o := range orders {
+		x, isOk := o.(expr.Sort)
+		if isOk {
+			if x.Direction == expr.Descending {
+				x.Direction = expr.Ascending
+			} else {
+				x.Direction = expr.Descending
+			}
+			o = x
+		}
+		arr = append(arr, o)
+	}
+	return arr
+}
+
+func reverseSlice(v reflect.Value) {
+	swap := reflect.Swapper(v.Interface())
+	for i, j := 0, v.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
 func (b *builder) replaceInto(ctx context.Context, table string) error {
 	buf, args := new(bytes.Buffer), make([]interface{}, 0)
 	buf.WriteString("REPLACE INTO ")
@@ -726,7 +1099,9 @@ func (b *builder) insertInto(ctx context.Context, table string) error {
 	})
 }
 
-func (b *builder) putStmt(ctx context.Context, parentKey []*datastore.Key, e *entity) (*stmt, error) {
+// putStmt : the insert statement of the entities, the update hooks is invoked instead
+// for the entities which exist, it's only known by `Upsert`
+func (b *builder) putStmt(ctx context.Context, parentKey []*datastore.Key, e *entity, exists map[string]bool) (*stmt, error) {
 	v := e.slice.Elem()
 
 	isInline := (parentKey == nil && len(parentKey) == 0)
@@ -739,6 +1114,7 @@ func (b *builder) putStmt(ctx context.Context, parentKey []*datastore.Key, e *en
 	}
 
 	cols := e.Columns()
+	now := b.db.now()
 	buf.WriteString(fmt.Sprintf("INSERT INTO %s (%s) VALUES ",
 		b.db.dialect.GetTable(e.Name()),
 		b.db.dialect.Quote(strings.Join(e.Columns(), b.db.dialect.Quote(",")))))
@@ -771,9 +1147,21 @@ func (b *builder) putStmt(ctx context.Context, parentKey []*datastore.Key, e *en
 				return nil, err
 			}
 		}
+		isExist := exists[stringifyKey(pk)]
+		if x, isOk := vi.Interface().(BeforeUpdate); isOk && isExist {
+			if err := x.BeforeUpdate(ctx, b.db); err != nil {
+				return nil, fmt.Errorf("goloquent: %w", err)
+			}
+		}
+		if x, isOk := vi.Interface().(BeforeCreate); isOk && !isExist {
+			if err := x.BeforeCreate(ctx, b.db); err != nil {
+				return nil, fmt.Errorf("goloquent: %w", err)
+			}
+		}
+		touch(e, vi, now, !isExist)
 		props, err := SaveStruct(vi.Interface())
 		if err != nil {
-			return nil, nil
+			return nil, err
 		}
 
 		props[pkColumn] = Property{[]string{pkColumn}, typeOfPtrKey, stringPk(pk)}
@@ -815,11 +1203,14 @@ func (b *builder) put(ctx context.Context, model interface{}, parentKey []*datas
 	if e.slice.Elem().Len() <= 0 {
 		return nil
 	}
-	cmd, err := b.putStmt(ctx, parentKey, e)
+	cmd, err := b.putStmt(ctx, parentKey, e, nil)
 	if err != nil {
 		return err
 	}
-	return b.db.client.execStmt(ctx, cmd)
+	if err := b.db.client.execStmt(ctx, cmd); err != nil {
+		return err
+	}
+	return afterCreate(ctx, b.db, e.slice.Elem())
 }
 
 func (b *builder) upsert(ctx context.Context, model interface{}, parentKey []*datastore.Key) error {
@@ -831,7 +1222,75 @@ func (b *builder) upsert(ctx context.Context, model interface{}, parentKey []*da
 	if e.slice.Elem().Len() <= 0 {
 		return nil
 	}
-	cmd, err := b.putStmt(ctx, parentKey, e)
+	// the record always be inserted with the parent key, as the primary key is generated,
+	// the existing records is only read when it's required by the hooks or createdAt,
+	// it's best effort outside of the transaction, as the record may be inserted concurrently
+	exists := make(map[string]bool)
+	_, hasCreatedAt := e.timestamp(true)
+	if parentKey == nil && (hasCreatedAt || hasWriteHooks(e.typeOf)) {
+		records, err := b.existingRecords(ctx, e)
+		if err != nil {
+			return err
+		}
+		c, _ := e.timestamp(true)
+		v := e.slice.Elem()
+		for i := 0; i < v.Len(); i++ {
+			k, _ := mustGetField(v.Index(i), e.field(keyFieldName)).Interface().(*datastore.Key)
+			r, isExist := records[stringifyKey(k)]
+			if !isExist {
+				continue
+			}
+			exists[stringifyKey(k)] = true
+			// the createdAt is never overwritten on conflict, so the stored value is loaded into the entity
+			if !hasCreatedAt {
+				continue
+			}
+			if fv := mustGetField(v.Index(i), c.field); isZeroTime(fv) {
+				fv.Set(mustGetField(r, c.field))
+			}
+		}
+	}
+	// the existing record is only updated when the version is matched
+	vs := newVersioning(e)
+	if vs != nil {
+		vs.incr()
+	}
+	if err := b.upsertEntity(ctx, parentKey, e, vs, exists); err != nil {
+		if vs != nil {
+			vs.restore()
+		}
+		return err
+	}
+	return hook(e.slice.Elem(), func(it interface{}) error {
+		k, _ := mustGetField(reflect.ValueOf(it), e.field(keyFieldName)).Interface().(*datastore.Key)
+		if x, isOk := it.(AfterUpdate); isOk && exists[stringifyKey(k)] {
+			return x.AfterUpdate(ctx, b.db)
+		}
+		if x, isOk := it.(AfterCreate); isOk && !exists[stringifyKey(k)] {
+			return x.AfterCreate(ctx, b.db)
+		}
+		return nil
+	})
+}
+
+func (b *builder) upsertEntity(ctx context.Context, parentKey []*datastore.Key, e *entity, vs *versioning, exists map[string]bool) error {
+	// the affected rows of the batch can't tell which entity is conflicted,
+	// so every versioned entity is written by its own statement within a transaction
+	if v := e.slice.Elem(); vs != nil && v.Len() > 1 {
+		return b.db.RunInTransactionCtx(ctx, func(tx *DB) error {
+			nb := &builder{db: tx, query: b.query}
+			for i := 0; i < v.Len(); i++ {
+				sub := *e
+				sub.slice = reflect.New(v.Type())
+				sub.slice.Elem().Set(v.Slice(i, i+1))
+				if err := nb.upsertEntity(ctx, parentKey, &sub, vs.at(i), exists); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil)
+	}
+	cmd, err := b.putStmt(ctx, parentKey, e, exists)
 	if err != nil {
 		return err
 	}
@@ -839,7 +1298,8 @@ func (b *builder) upsert(ctx context.Context, model interface{}, parentKey []*da
 	omits := newDictionary(b.query.omits)
 	columns := make([]string, 0, len(cols))
 	for _, c := range cols {
-		if omits.has(c) || c == pkColumn || c == keyFieldName {
+		// the createdAt is never overwritten on conflict
+		if omits.has(c) || c == pkColumn || c == keyFieldName || c == e.createdAt() || (vs != nil && c == vs.column) {
 			continue
 		}
 		columns = append(columns, c)
@@ -847,22 +1307,84 @@ func (b *builder) upsert(ctx context.Context, model interface{}, parentKey []*da
 	cmd.statement.Truncate(cmd.statement.Len() - 1)
 	buf := new(bytes.Buffer)
 	buf.WriteString(cmd.string())
-	if len(columns) > 0 {
+	switch {
+	case vs != nil:
+		buf.WriteString(" " + b.db.dialect.OnConflictUpdateVersion(e.Name(), columns, vs.column))
+	case len(columns) > 0:
 		buf.WriteString(" " + b.db.dialect.OnConflictUpdate(e.Name(), columns))
 	}
 	buf.WriteString(";")
 	cmd.statement = buf
-	return b.db.client.execStmt(ctx, cmd)
+	result, err := b.db.client.execResult(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if vs != nil {
+		return vs.check(result)
+	}
+	return nil
 }
 
-func (b *builder) saveMutation(ctx context.Context, model interface{}) (*stmt, error) {
+// existingRecords : the stored records of the entities which have complete key, keyed by the stringified key,
+// only the key and createdAt is selected and the hooks is not invoked
+func (b *builder) existingRecords(ctx context.Context, e *entity) (map[string]reflect.Value, error) {
+	v := e.slice.Elem()
+	keys := make([]*datastore.Key, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		k, isOk := mustGetField(v.Index(i), e.field(keyFieldName)).Interface().(*datastore.Key)
+		if isOk && k != nil && !k.Incomplete() {
+			keys = append(keys, k)
+		}
+	}
+	records := make(map[string]reflect.Value)
+	if len(keys) <= 0 {
+		return records, nil
+	}
+	fields, cols := []field{e.field(keyFieldName)}, []string{b.db.dialect.Quote(pkColumn)}
+	if c, isOk := e.timestamp(true); isOk {
+		fields = append(fields, c.field)
+		cols = append(cols, b.db.dialect.Quote(c.Name()))
+	}
+	q := newQuery(b.db).Where(keyFieldName, "in", keys)
+	q.table = e.Name()
+	nb := newBuilder(q)
+	cmd, err := nb.buildWhere(nb.query)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ","), b.db.dialect.GetTable(e.Name())))
+	buf.WriteString(cmd.string() + ";")
+	it, err := nb.run(ctx, e.Name(), &stmt{statement: buf, arguments: cmd.arguments})
+	if err != nil {
+		return nil, err
+	}
+	for it.Next() {
+		nv := reflect.New(e.typeOf)
+		for _, f := range fields {
+			vi, err := it.decodeField(f)
+			if err != nil {
+				return nil, err
+			}
+			if err := loadField(getField(nv.Elem(), f.paths), vi); err != nil {
+				return nil, err
+			}
+		}
+		if k, isOk := mustGetField(nv, e.field(keyFieldName)).Interface().(*datastore.Key); isOk && k != nil {
+			records[stringifyKey(k)] = nv
+		}
+	}
+	return records, nil
+}
+
+func (b *builder) saveMutation(ctx context.Context, model interface{}) (*stmt, *versioning, error) {
 	v := reflect.Indirect(reflect.ValueOf(model))
 	if v.Len() <= 0 {
-		return new(stmt), nil
+		return new(stmt), nil, nil
 	}
 	e, err := newEntity(model)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	e.setName(b.query.table)
 	buf := new(bytes.Buffer)
@@ -871,45 +1393,67 @@ func (b *builder) saveMutation(ctx context.Context, model interface{}) (*stmt, e
 	f := v.Index(0)
 	if x, isOk := f.Interface().(Saver); isOk {
 		if err := x.Save(ctx); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+	}
+	if x, isOk := f.Interface().(BeforeUpdate); isOk {
+		if err := x.BeforeUpdate(ctx, b.db); err != nil {
+			return nil, nil, fmt.Errorf("goloquent: %w", err)
 		}
 	}
+	touch(e, f, b.db.now(), false)
 	props, err := SaveStruct(f.Interface())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	// the version of the entity is increased after the record is written
+	vs := newVersioning(e)
+	if vs != nil {
+		p := props[vs.column]
+		p.Value = vs.next(0)
+		props[vs.column] = p
 	}
 
 	pk, isOk := props[keyFieldName].Value.(*datastore.Key)
 	if !isOk {
-		return nil, fmt.Errorf("goloquent: entity %q has no primary key property", f.Type().Name())
+		return nil, nil, fmt.Errorf("goloquent: entity %q has no primary key property", f.Type().Name())
 	}
 	delete(props, keyFieldName)
+	delete(props, e.createdAt())
 	if pk == nil || pk.Incomplete() {
-		return nil, fmt.Errorf("goloquent: invalid key value, %v", pk)
+		return nil, nil, fmt.Errorf("goloquent: invalid key value, %v", pk)
 	}
 
 	omits := newDictionary(b.query.omits)
 	j := int(1)
 	for k, p := range props {
-		if omits.has(k) {
+		if omits.has(k) && (vs == nil || k != vs.column) {
 			continue
 		}
 		it, err := p.Interface()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		buf.WriteString(fmt.Sprintf("%s = %s,", b.db.dialect.Quote(k), variable))
 		args = append(args, it)
 		j++
 	}
 	buf.Truncate(buf.Len() - 1)
-	buf.WriteString(fmt.Sprintf(" WHERE %s = %s LIMIT 1;", b.db.dialect.Quote(pkColumn), variable))
+	buf.WriteString(fmt.Sprintf(" WHERE %s = %s", b.db.dialect.Quote(pkColumn), variable))
 	args = append(args, stringPk(pk))
+	if vs != nil {
+		buf.WriteString(fmt.Sprintf(" AND %s = %s", b.db.dialect.Quote(vs.column), variable))
+		args = append(args, vs.prev(0))
+	}
+	if b.db.dialect.UpdateWithLimit() {
+		buf.WriteString(" LIMIT 1")
+	}
+	buf.WriteString(";")
 
 	return &stmt{
 		statement: buf,
 		arguments: args,
-	}, nil
+	}, vs, nil
 }
 
 func (b *builder) save(ctx context.Context, model interface{}) error {
@@ -921,13 +1465,25 @@ func (b *builder) save(ctx context.Context, model interface{}) error {
 	vi.Index(0).Set(v)
 	vv := reflect.New(vi.Type())
 	vv.Elem().Set(vi)
-	cmd, err := b.saveMutation(ctx, vv.Interface())
+	cmd, vs, err := b.saveMutation(ctx, vv.Interface())
 	if err != nil {
 		return err
 	}
-	if err := b.db.client.execStmt(ctx, cmd); err != nil {
+	result, err := b.db.client.execResult(ctx, cmd)
+	if err != nil {
 		return err
 	}
+	if vs != nil {
+		if err := vs.check(result); err != nil {
+			return err
+		}
+		vs.incr()
+	}
+	if x, isOk := vi.Index(0).Interface().(AfterUpdate); isOk {
+		if err := x.AfterUpdate(ctx, b.db); err != nil {
+			return fmt.Errorf("goloquent: %w", err)
+		}
+	}
 	v.Elem().Set(vi.Index(0).Elem())
 	return nil
 }
@@ -944,6 +1500,18 @@ func (b *builder) updateWithMap(v reflect.Value) (*stmt, error) {
 		if kk == keyFieldName {
 			return nil, fmt.Errorf("goloquent: update __key__ is not allow")
 		}
+		if x, isOk := vv.Interface().(expr.Update); isOk {
+			if x.Name != kk {
+				return nil, fmt.Errorf("goloquent: mismatch column %q of the update operator, expected %q", x.Name, kk)
+			}
+			str, vals, err := b.updateExpr(b.db.dialect.Quote(kk), x)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString(fmt.Sprintf(" %s = %s,", b.db.dialect.Quote(kk), str))
+			args = append(args, vals...)
+			continue
+		}
 		buf.WriteString(fmt.Sprintf(" %s = %s,", b.db.dialect.Quote(kk), variable))
 		v, err := normalizeValue(vv.Interface())
 		if err != nil {
@@ -966,6 +1534,101 @@ func (b *builder) updateWithMap(v reflect.Value) (*stmt, error) {
 	}, nil
 }
 
+// updateExpr : the expression of the atomic update operator on top of the column expression, the element of
+// the slice column is converted the same way as the filter value, so it's comparable with the stored element
+func (b *builder) updateExpr(col string, u expr.Update) (string, []interface{}, error) {
+	if u.Name == "" || u.Name == keyFieldName || u.Name == pkColumn {
+		return "", nil, fmt.Errorf("goloquent: invalid column %q of the update operator", u.Name)
+	}
+	args := make([]interface{}, 0, len(u.Values))
+	switch u.Op {
+	case expr.OpIncrement, expr.OpDecrement:
+		if len(u.Values) != 1 || u.Values[0] == nil {
+			return "", nil, fmt.Errorf("goloquent: missing value to increase or decrease column %q", u.Name)
+		}
+		switch reflect.ValueOf(u.Values[0]).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return "", nil, fmt.Errorf("goloquent: invalid value %v to increase or decrease column %q", u.Values[0], u.Name)
+		}
+		args = append(args, u.Values[0])
+	case expr.OpJSONSet, expr.OpJSONRemove:
+		if !strings.HasPrefix(u.Path, "$.") {
+			return "", nil, fmt.Errorf("goloquent: invalid json path %q, it should start with \"$.\"", u.Path)
+		}
+		if u.Op == expr.OpJSONSet {
+			if len(u.Values) != 1 {
+				return "", nil, fmt.Errorf("goloquent: missing value to set json path %q of column %q", u.Path, u.Name)
+			}
+			raw, isOk := u.Values[0].(json.RawMessage)
+			if !isOk {
+				bb, err := json.Marshal(u.Values[0])
+				if err != nil {
+					return "", nil, fmt.Errorf("goloquent: unable to marshal the value %v, %w", u.Values[0], err)
+				}
+				raw = bb
+			}
+			args = append(args, b2s(raw))
+		}
+	case expr.OpArrayAppend, expr.OpArrayRemove:
+		if len(u.Values) <= 0 {
+			return "", nil, fmt.Errorf("goloquent: missing value to append or remove of column %q", u.Name)
+		}
+		for _, it := range u.Values {
+			v, err := normalizeValue(it)
+			if err != nil {
+				return "", nil, err
+			}
+			v, err = interfaceToValue(v)
+			if err != nil {
+				return "", nil, err
+			}
+			bb, err := json.Marshal(v)
+			if err != nil {
+				return "", nil, fmt.Errorf("goloquent: unable to marshal the value %v, %w", it, err)
+			}
+			args = append(args, b2s(bb))
+		}
+	default:
+		return "", nil, fmt.Errorf("goloquent: unsupported update operator %v", u.Op)
+	}
+	return b.db.dialect.UpdateExpr(col, u, args)
+}
+
+// updateWithExpr : the operators of the same column is nested into single assignment in order,
+// as the column cannot be assigned multiple times
+func (b *builder) updateWithExpr(ops []expr.Update) (*stmt, error) {
+	cols, exprs := make([]string, 0), make(map[string]*stmt)
+	for _, op := range ops {
+		col, isOk := exprs[op.Name]
+		if !isOk {
+			col = &stmt{statement: new(bytes.Buffer)}
+			col.statement.WriteString(b.db.dialect.Quote(op.Name))
+			cols = append(cols, op.Name)
+			exprs[op.Name] = col
+		}
+		str, vals, err := b.updateExpr(col.string(), op)
+		if err != nil {
+			return nil, err
+		}
+		col.statement.Reset()
+		col.statement.WriteString(str)
+		col.arguments = append(col.arguments, vals...)
+	}
+	buf, args := new(bytes.Buffer), make([]interface{}, 0)
+	for _, name := range cols {
+		buf.WriteString(fmt.Sprintf(" %s = %s,", b.db.dialect.Quote(name), exprs[name].string()))
+		args = append(args, exprs[name].arguments...)
+	}
+	buf.Truncate(buf.Len() - 1)
+	return &stmt{
+		statement: buf,
+		arguments: args,
+	}, nil
+}
+
 func (b *builder) updateWithStruct(model interface{}) (*stmt, error) {
 	vi := reflect.Indirect(reflect.ValueOf(model))
 	vv := reflect.New(vi.Type())
@@ -973,6 +1636,11 @@ func (b *builder) updateWithStruct(model interface{}) (*stmt, error) {
 	if err := checkSinglePtr(vv.Interface()); err != nil {
 		return nil, err
 	}
+	e, err := newEntity(vv.Interface())
+	if err != nil {
+		return nil, err
+	}
+	touch(e, vv, b.db.now(), false)
 	cols := newDictionary(b.query.projection)
 	buf, args := new(bytes.Buffer), make([]interface{}, 0)
 	props, err := SaveStruct(vv.Interface())
@@ -981,7 +1649,7 @@ func (b *builder) updateWithStruct(model interface{}) (*stmt, error) {
 	}
 	for _, p := range props {
 		name := p.Name()
-		if name == keyFieldName || (!cols.has(name) && p.isZero()) {
+		if name == keyFieldName || name == e.createdAt() || (!cols.has(name) && p.isZero()) {
 			continue
 		}
 		it, err := p.Interface()
@@ -1009,8 +1677,25 @@ func (b *builder) updateMulti(ctx context.Context, v interface{}) error {
 		return fmt.Errorf("goloquent: missing table name")
 	}
 	buf.WriteString(fmt.Sprintf("UPDATE %s SET", b.db.dialect.GetTable(table)))
-	switch vi.Type().Kind() {
-	case reflect.Map:
+	ops, isExpr := vi.Interface().([]expr.Update)
+	if x, isOk := vi.Interface().(expr.Update); isOk {
+		ops, isExpr = []expr.Update{x}, true
+	}
+	switch {
+	case isExpr:
+		if b.query.table == "" {
+			return fmt.Errorf("goloquent: missing table name")
+		}
+		if len(ops) == 0 {
+			return nil
+		}
+		cmd, err := b.updateWithExpr(ops)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(cmd.string())
+		args = append(args, cmd.arguments...)
+	case vi.Kind() == reflect.Map:
 		if vi.IsNil() || vi.Len() == 0 {
 			return nil
 		}
@@ -1020,7 +1705,7 @@ func (b *builder) updateMulti(ctx context.Context, v interface{}) error {
 		}
 		buf.WriteString(cmd.string())
 		args = append(args, cmd.arguments...)
-	case reflect.Struct:
+	case vi.Kind() == reflect.Struct:
 		cmd, err := b.updateWithStruct(v)
 		if err != nil {
 			return err
@@ -1065,7 +1750,7 @@ func (b *builder) concatKeys(e *entity) (*stmt, error) {
 		if !isOk {
 			return nil, fmt.Errorf("goloquent: entity %q has no primary key property", f.Type().Name())
 		}
-		if kk.Incomplete() {
+		if kk == nil || kk.Incomplete() {
 			return nil, fmt.Errorf("goloquent: entity %q has incomplete key", f.Type().Name())
 		}
 		buf.WriteString(variable)
@@ -1083,7 +1768,7 @@ func (b *builder) softDeleteStmt(e *entity) (*stmt, error) {
 	buf.WriteString(fmt.Sprintf("UPDATE %s SET ", b.db.dialect.GetTable(e.Name())))
 	buf.WriteString(fmt.Sprintf("%s = %s WHERE %s IN ",
 		b.db.dialect.Quote(softDeleteColumn), variable, b.db.dialect.Quote(pkColumn)))
-	args = append(args, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	args = append(args, b.db.now().Format("2006-01-02 15:04:05"))
 	ss, err := b.concatKeys(e)
 	if err != nil {
 		return nil, err
@@ -1096,6 +1781,24 @@ func (b *builder) softDeleteStmt(e *entity) (*stmt, error) {
 	}, nil
 }
 
+func (b *builder) restoreStmt(e *entity) (*stmt, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s IN ",
+		b.db.dialect.GetTable(e.Name()),
+		b.db.dialect.Quote(softDeleteColumn),
+		b.db.dialect.Quote(pkColumn)))
+	ss, err := b.concatKeys(e)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString(ss.string())
+	buf.WriteString(";")
+	return &stmt{
+		statement: buf,
+		arguments: ss.arguments,
+	}, nil
+}
+
 func (b *builder) deleteStmt(e *entity, isSoftDelete bool) (*stmt, error) {
 	buf, args := new(bytes.Buffer), make([]interface{}, 0)
 	if isSoftDelete && e.hasSoftDelete() {
@@ -1122,13 +1825,81 @@ func (b *builder) delete(ctx context.Context, model interface{}, isSoftDelete bo
 		return err
 	}
 	e.setName(b.query.table)
+	if err := beforeDelete(ctx, b.db, e.slice.Elem()); err != nil {
+		return err
+	}
 	cmd, err := b.deleteStmt(e, isSoftDelete)
 	if err != nil {
 		return err
 	}
+	if err := b.db.client.execStmt(ctx, cmd); err != nil {
+		return err
+	}
+	return afterDelete(ctx, b.db, e.slice.Elem())
+}
+
+// restore : restore the soft deleted entities, the soft delete field of the entities will be reset
+func (b *builder) restore(ctx context.Context, model interface{}) error {
+	e, err := newEntity(model)
+	if err != nil {
+		return err
+	}
+	e.setName(b.query.table)
+	if !e.hasSoftDelete() {
+		return fmt.Errorf("goloquent: entity %q has no soft delete field", e.Name())
+	}
+	cmd, err := b.restoreStmt(e)
+	if err != nil {
+		return err
+	}
+	if err := b.db.client.execStmt(ctx, cmd); err != nil {
+		return err
+	}
+	v := e.slice.Elem()
+	for i := 0; i < v.Len(); i++ {
+		fv := mustGetField(v.Index(i), e.field(softDeleteColumn))
+		fv.Set(reflect.Zero(fv.Type()))
+	}
+	return nil
+}
+
+// restoreByQuery : restore the soft deleted records which match the query
+func (b *builder) restoreByQuery(ctx context.Context) error {
+	query := b.query
+	query.onlyTrashed = true
+	cmd, err := b.buildStmt(query.softDeleteScope(true))
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("UPDATE %s SET %s = NULL",
+		b.db.dialect.GetTable(query.table),
+		b.db.dialect.Quote(softDeleteColumn)))
+	buf.WriteString(cmd.string())
+	buf.WriteString(";")
+	cmd.statement = buf
 	return b.db.client.execStmt(ctx, cmd)
 }
 
+// forceDeleteTrashed : permanently delete the soft deleted records which match the query,
+// and soft deleted longer than `olderThan` by the clock of the connection if it's not zero
+func (b *builder) forceDeleteTrashed(ctx context.Context, olderThan time.Duration) error {
+	query := b.query
+	query.onlyTrashed = true
+	query = query.softDeleteScope(true)
+	if olderThan > 0 {
+		// the cutoff follow the clock of the connection, same as the deletedAt
+		before := b.db.now().Add(-olderThan)
+		query.filters = append(query.filters, Filter{
+			field:    softDeleteColumn,
+			operator: LessThan,
+			value:    before.UTC().Format("2006-01-02 15:04:05"),
+		})
+	}
+	b.query = query
+	return b.deleteByQuery(ctx)
+}
+
 func (b *builder) deleteByQuery(ctx context.Context) error {
 	query := b.query
 	cmd, err := b.buildStmt(query)
@@ -1146,7 +1917,7 @@ func (b *builder) deleteByQuery(ctx context.Context) error {
 func (b *builder) truncate(ctx context.Context, tables ...string) error {
 	for _, n := range tables {
 		buf := new(bytes.Buffer)
-		buf.WriteString(fmt.Sprintf("TRUNCATE TABLE %s;", b.db.dialect.GetTable(n)))
+		buf.WriteString(b.db.dialect.TruncateTable(n))
 		if err := b.db.client.execStmt(ctx, &stmt{
 			statement: buf,
 		}); err != nil {
@@ -1158,10 +1929,9 @@ func (b *builder) truncate(ctx context.Context, tables ...string) error {
 
 func (b *builder) scan(ctx context.Context, dest ...interface{}) error {
 	query := b.query
-	table := query.table
 	buf := new(bytes.Buffer)
 	buf.WriteString(b.buildSelect(query).string())
-	buf.WriteString(fmt.Sprintf(" FROM %s", b.db.dialect.GetTable(table)))
+	buf.WriteString(b.buildFrom())
 	ss, err := b.buildStmt(b.query)
 	if err != nil {
 		return err
@@ -1172,39 +1942,376 @@ func (b *builder) scan(ctx context.Context, dest ...interface{}) error {
 		statement: buf,
 		arguments: ss.arguments,
 	}).Scan(dest...); err != nil {
-		return fmt.Errorf("goloquent: %v", err)
+		return fmt.Errorf("goloquent: %w", b.db.dialect.WrapError(err))
+	}
+	return nil
+}
+
+// aggregate : apply the aggregate function on the record set of the query,
+// the record set will be a sub query when there is limit or offset
+func (b *builder) aggregate(ctx context.Context, model interface{}, fn, field string, dest interface{}) error {
+	e, err := newEntity(model)
+	if err != nil {
+		return err
+	}
+	e.setName(b.query.table)
+	b.query.table = e.Name()
+	col := "*"
+	if field != "" {
+		name, err := e.columnName(field)
+		if err != nil {
+			return err
+		}
+		col = b.quoteColumn(b.splitColumn(name))
+	}
+
+	query := b.query
+	query.projection, query.distinctOn = nil, nil
+	query = query.softDeleteScope(e.hasSoftDelete())
+
+	buf, args := new(bytes.Buffer), make([]interface{}, 0)
+	buf.WriteString(fmt.Sprintf("SELECT %s FROM ", fmt.Sprintf(fn, col)))
+	if query.limit > 0 || query.offset > 0 {
+		ss, err := b.buildStmt(query)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(fmt.Sprintf("(%s%s%s) AS %s",
+			b.buildSelect(query).string(), b.buildFrom(), ss.string(), b.db.dialect.Quote(b.tableAlias())))
+		args = append(args, ss.arguments...)
+	} else {
+		ss, err := b.buildWhere(query)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strings.TrimPrefix(b.buildFrom(), " FROM "))
+		buf.WriteString(ss.string())
+		args = append(args, ss.arguments...)
+	}
+	buf.WriteString(";")
+	if err := b.db.client.execQueryRow(ctx, &stmt{
+		statement: buf,
+		arguments: args,
+	}).Scan(dest); err != nil {
+		return fmt.Errorf("goloquent: %w", b.db.dialect.WrapError(err))
+	}
+	return nil
+}
+
+func (b *builder) exists(ctx context.Context, model interface{}) (bool, error) {
+	e, err := newEntity(model)
+	if err != nil {
+		return false, err
+	}
+	e.setName(b.query.table)
+	b.query.table = e.Name()
+	query := b.query
+	query.orders = nil
+	query = query.softDeleteScope(e.hasSoftDelete())
+	ss, err := b.buildWhere(query)
+	if err != nil {
+		return false, err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("SELECT EXISTS(SELECT 1")
+	buf.WriteString(b.buildFrom())
+	buf.WriteString(ss.string())
+	buf.WriteString(");")
+	var isExist bool
+	if err := b.db.client.execQueryRow(ctx, &stmt{
+		statement: buf,
+		arguments: ss.arguments,
+	}).Scan(&isExist); err != nil {
+		return false, fmt.Errorf("goloquent: %w", b.db.dialect.WrapError(err))
+	}
+	return isExist, nil
+}
+
+func (b *builder) scanAll(ctx context.Context, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("goloquent: scan destination is not addressable")
+	}
+	v = v.Elem()
+	t, isSlice := v.Type(), false
+	if t.Kind() == reflect.Slice {
+		t, isSlice = t.Elem(), true
+	}
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct:
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Interface:
+	default:
+		return fmt.Errorf("goloquent: unsupported scan destination %v", v.Type())
+	}
+
+	if b.query.table == "" {
+		return fmt.Errorf("goloquent: missing table name")
+	}
+	var parts []joinPart
+	if t.Kind() == reflect.Struct {
+		parts = b.joinParts(t)
+	}
+	query := b.query
+	if len(parts) > 0 {
+		query.projection = make([]string, 0)
+		for _, p := range parts {
+			for _, col := range p.columns {
+				query.projection = append(query.projection, fmt.Sprintf("%s AS %s",
+					b.quoteColumn(p.alias, col), b.db.dialect.Quote(p.alias+"."+col)))
+			}
+		}
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(b.buildSelect(query).string())
+	buf.WriteString(b.buildFrom())
+	ss, err := b.buildStmt(b.query)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(ss.string())
+	buf.WriteString(";")
+	rows, err := b.db.client.execQuery(ctx, &stmt{
+		statement: buf,
+		arguments: ss.arguments,
+	})
+	if err != nil {
+		return fmt.Errorf("goloquent: %w", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("goloquent: %w", err)
+	}
+
+	vv := reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 0)
+	if isSlice {
+		vv = reflect.MakeSlice(v.Type(), 0, 0)
+	}
+	for rows.Next() {
+		m := make([]interface{}, len(cols))
+		for j := range cols {
+			m[j] = &m[j]
+		}
+		if err := rows.Scan(m...); err != nil {
+			return fmt.Errorf("goloquent: %w", err)
+		}
+
+		vi := reflect.New(t)
+		if t.Kind() == reflect.Map {
+			data := make(map[string]interface{})
+			for j, name := range cols {
+				if b, isOk := m[j].([]byte); isOk {
+					m[j] = string(b)
+				}
+				data[name] = m[j]
+			}
+			vi.Elem().Set(reflect.ValueOf(data))
+		} else if len(parts) > 0 {
+			if err := scanJoinParts(ctx, b.db, parts, vi.Elem(), cols, m); err != nil {
+				return err
+			}
+		} else {
+			it := Iterator{db: b.db, columns: cols}
+			for j, name := range cols {
+				it.put(0, name, m[j])
+			}
+			if _, err := it.scan(ctx, vi.Interface()); err != nil {
+				return err
+			}
+		}
+		if !isPtr {
+			vi = vi.Elem()
+		}
+		vv = reflect.Append(vv, vi)
+		if !isSlice {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("goloquent: %w", err)
+	}
+
+	if !isSlice {
+		if vv.Len() <= 0 {
+			return ErrNoSuchEntity
+		}
+		v.Set(vv.Index(0))
+		return nil
+	}
+	v.Set(vv)
+	return nil
+}
+
+// joinPart : the struct field of the composite destination which hold the entity of the table alias
+type joinPart struct {
+	index   int
+	alias   string
+	table   string
+	isPtr   bool
+	typeOf  reflect.Type
+	columns []string
+}
+
+// joinParts : resolve the composite destination, each field which named by the table alias
+// (or tag name) and is an entity will be scanned with the columns of the alias
+func (b *builder) joinParts(t reflect.Type) []joinPart {
+	if len(b.query.joins) <= 0 {
+		return nil
+	}
+	aliases := b.aliases()
+	parts := make([]joinPart, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		table, isOk := aliases[newTag(sf).name]
+		if !isOk {
+			continue
+		}
+		ft, isPtr := sf.Type, false
+		if ft.Kind() == reflect.Ptr {
+			ft, isPtr = ft.Elem(), true
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		e, err := newEntity(reflect.New(ft).Interface())
+		if err != nil {
+			continue
+		}
+		parts = append(parts, joinPart{
+			index:   i,
+			alias:   newTag(sf).name,
+			table:   table,
+			isPtr:   isPtr,
+			typeOf:  ft,
+			columns: e.Columns(),
+		})
+	}
+	return parts
+}
+
+func scanJoinParts(ctx context.Context, db *DB, parts []joinPart, v reflect.Value, cols []string, m []interface{}) error {
+	for _, p := range parts {
+		it := Iterator{db: db, table: p.table, columns: p.columns}
+		prefix := p.alias + "."
+		for j, name := range cols {
+			if strings.HasPrefix(name, prefix) {
+				it.put(0, strings.TrimPrefix(name, prefix), m[j])
+			}
+		}
+		// no matched record on left join, leave it as zero value
+		if it.Get(pkColumn) == nil {
+			continue
+		}
+		it.patchKey()
+		vi := reflect.New(p.typeOf)
+		if _, err := it.scan(ctx, vi.Interface()); err != nil {
+			return err
+		}
+		if p.isPtr {
+			v.Field(p.index).Set(vi)
+			continue
+		}
+		v.Field(p.index).Set(vi.Elem())
 	}
 	return nil
 }
 
-func (b *builder) runInTransaction(cb TransactionHandler) error {
+func (b *builder) runInTransaction(ctx context.Context, cb TransactionHandler, opts *sql.TxOptions) error {
+	if _, isOk := b.db.client.sqlCommon.(*sql.Tx); isOk {
+		return b.runInSavepoint(ctx, cb, opts)
+	}
+	policy := b.db.retryPolicy
+	for n := 1; ; n++ {
+		err := b.runTransaction(ctx, cb, opts)
+		if err == nil || policy == nil || n >= policy.MaxAttempts || !policy.isRetryable(b.db.dialect, err) {
+			return err
+		}
+		if err := sleep(ctx, policy.delay(n)); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *builder) runTransaction(ctx context.Context, cb TransactionHandler, opts *sql.TxOptions) error {
 	conn, isOk := b.db.client.sqlCommon.(*sql.DB)
 	if !isOk {
 		return fmt.Errorf("goloquent: unable to initiate transaction")
 	}
-	tx, err := conn.Begin()
+	tx, err := conn.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("goloquent: unable to begin transaction, %v", err)
 	}
 	db := b.db.clone()
 	db.client.sqlCommon = tx
+	// the panic is propagated after the rollback, same as the savepoint
 	defer func() {
 		if r := recover(); r != nil {
-			defer tx.Rollback()
+			tx.Rollback()
+			panic(r)
 		}
 	}()
 	defer tx.Rollback()
 	if err := cb(db); err != nil {
 		return err
 	}
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("goloquent: %w", b.db.dialect.WrapError(err))
+	}
+	return nil
+}
+
+// runInSavepoint : nested transaction, the savepoint will be rolled back independently
+// without affecting the outer transaction
+func (b *builder) runInSavepoint(ctx context.Context, cb TransactionHandler, opts *sql.TxOptions) error {
+	if opts != nil && (opts.Isolation != sql.LevelDefault || opts.ReadOnly) {
+		return fmt.Errorf("goloquent: transaction options is not supported in nested transaction")
+	}
+	db := b.db.clone()
+	db.savepoint++
+	name := b.db.dialect.Quote(fmt.Sprintf("sp%d", db.savepoint))
+	if err := b.db.client.execCommand(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("goloquent: unable to create savepoint, %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			b.db.client.execCommand(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(r)
+		}
+	}()
+	if err := cb(db); err != nil {
+		if rerr := b.db.client.execCommand(ctx, "ROLLBACK TO SAVEPOINT "+name); rerr != nil {
+			return fmt.Errorf("goloquent: unable to rollback savepoint, %v", rerr)
+		}
+		return err
+	}
+	return b.db.client.execCommand(ctx, "RELEASE SAVEPOINT "+name)
 }
 
-func sha1Sign(s *Stmt) string {
-	h, rgx := sha1.New(), regexp.MustCompile(`(?i)FROM.+?(LIMIT)`)
-	bb := bytes.TrimSpace(bytes.TrimLeft(bytes.TrimRight(rgx.Find([]byte(s.String())), "LIMIT"), "FROM"))
-	h.Write(bb)
-	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+// querySign : digest of the normalized query (table, filters and orders),
+// the cursor only valid for the query which issue it
+func (b *builder) querySign(table string) (string, error) {
+	where, err := b.buildWhere(b.query)
+	if err != nil {
+		return "", err
+	}
+	order, err := b.buildOrderBy(b.query)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(b.db.dialect.GetTable(table)))
+	for _, s := range []*stmt{where, order} {
+		h.Write([]byte{0})
+		h.Write([]byte((&Stmt{stmt: *s, replacer: b.db.dialect}).String()))
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
 }
 
 func interfaceToKeyString(it interface{}) (interface{}, error) {
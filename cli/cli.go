@@ -0,0 +1,258 @@
+// Package cli is the command line tool of goloquent, it is used by `cmd/goloquent`,
+// register the models and Go function migrations in the `App` to build the tool of your project.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Oskang09/goloquent"
+	"github.com/Oskang09/goloquent/db"
+)
+
+// errUsage : the arguments is invalid, the usage is printed
+var errUsage = errors.New("goloquent: invalid usage")
+
+// Config : the connection config, it can be loaded from the JSON file with `-config`
+type Config struct {
+	Driver     string `json:"driver"`
+	Host       string `json:"host"`
+	Port       string `json:"port"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Database   string `json:"database"`
+	UnixSocket string `json:"socket"`
+	TLSConfig  string `json:"tls"`
+	// MigrationDir : directory of the SQL file migrations, see `Migrator.AddFS`
+	MigrationDir string `json:"migration_dir"`
+	// MigrationTable : name of the migration history table, see `Migrator.SetTable`
+	MigrationTable string `json:"migration_table"`
+}
+
+// LoadConfig : read the connection config from the JSON file
+func LoadConfig(file string) (Config, error) {
+	conf := Config{}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return conf, fmt.Errorf("goloquent: %w", err)
+	}
+	if err := json.Unmarshal(b, &conf); err != nil {
+		return conf, fmt.Errorf("goloquent: invalid config file %q, %w", file, err)
+	}
+	return conf, nil
+}
+
+// App :
+type App struct {
+	// Models : the models to be compared with the live schema by `diff`
+	Models []interface{}
+	// Migrations : the Go function migrations, they are registered along with the SQL file migrations
+	Migrations []goloquent.Migration
+	// Stdout : default is `os.Stdout`
+	Stdout io.Writer
+	// Stderr : default is `os.Stderr`
+	Stderr io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"generate": {"generate [-package name] [-o file] [table...]\n\tgenerate the model structs from the existing tables, default is all tables", runGenerate},
+		"migrate":  {"migrate [-dir dir] [-table name] up | down [steps] | unlock\n\tapply or revert the versioned migrations, or release the lock of the crashed migration", runMigrate},
+		"status":   {"status [-dir dir] [-table name]\n\tshow the status of the versioned migrations", runStatus},
+		"diff":     {"diff [-unsafe] [-sql]\n\tcompare the registered models against the live schema, nothing is executed,\n\tthe models are only registered by embedding the command with cli.App{Models: ...}", runDiff},
+		"tables":   {"tables\n\tlist the tables", runTables},
+		"indexes":  {"indexes table...\n\tlist the indexes of the tables", runIndexes},
+		"describe": {"describe table...\n\tshow the columns and indexes of the tables", runDescribe},
+		"truncate": {"truncate -force table...\n\tdelete all the records of the tables", runTruncate},
+		"drop":     {"drop -force table...\n\tdrop the tables", runDrop},
+	}
+}
+
+// env : the state shared by the commands
+type env struct {
+	app    *App
+	conf   Config
+	stdout io.Writer
+	flags  *flag.FlagSet
+	db     *goloquent.DB
+}
+
+func (e *env) open(ctx context.Context) (*goloquent.DB, error) {
+	if e.db != nil {
+		return e.db, nil
+	}
+	driver := strings.ToLower(strings.TrimSpace(e.conf.Driver))
+	if _, isOk := goloquent.GetDialect(driver); !isOk {
+		return nil, fmt.Errorf("goloquent: unsupported database driver %q", e.conf.Driver)
+	}
+	conn, err := db.Open(ctx, driver, db.Config{
+		Username:   e.conf.Username,
+		Password:   e.conf.Password,
+		Host:       e.conf.Host,
+		Port:       e.conf.Port,
+		Database:   e.conf.Database,
+		UnixSocket: e.conf.UnixSocket,
+		TLSConfig:  e.conf.TLSConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.db = conn
+	return conn, nil
+}
+
+func (a *App) usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: goloquent [flags] <command> [args]")
+	fmt.Fprintln(w, "\nflags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+}
+
+// Run : run the command with the arguments, excluding the program name
+func (a *App) Run(ctx context.Context, args []string) error {
+	stdout, stderr := a.Stdout, a.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	// the precedence is flag, environment variable, config file
+	fs := flag.NewFlagSet("goloquent", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	flags := Config{}
+	file := fs.String("config", os.Getenv("GOLOQUENT_CONFIG"), "connection config file in JSON, default is $GOLOQUENT_CONFIG")
+	fs.StringVar(&flags.Driver, "driver", "", "database driver, mysql, postgres or sqlite, default is mysql")
+	fs.StringVar(&flags.Host, "host", "", "database host")
+	fs.StringVar(&flags.Port, "port", "", "database port")
+	fs.StringVar(&flags.Username, "user", "", "database username")
+	fs.StringVar(&flags.Password, "password", "", "database password, default is $GOLOQUENT_PASSWORD")
+	fs.StringVar(&flags.Database, "database", "", "database name, or the file path of sqlite")
+	fs.StringVar(&flags.UnixSocket, "socket", "", "unix socket of the database")
+	if err := fs.Parse(args); err != nil || fs.NArg() <= 0 {
+		a.usage(stderr, fs)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+	cmd, isOk := commands[fs.Arg(0)]
+	if !isOk {
+		a.usage(stderr, fs)
+		return errUsage
+	}
+
+	conf := Config{}
+	if *file != "" {
+		c, err := LoadConfig(*file)
+		if err != nil {
+			return err
+		}
+		conf = c
+	}
+	if v := os.Getenv("GOLOQUENT_PASSWORD"); v != "" {
+		conf.Password = v
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "driver":
+			conf.Driver = flags.Driver
+		case "host":
+			conf.Host = flags.Host
+		case "port":
+			conf.Port = flags.Port
+		case "user":
+			conf.Username = flags.Username
+		case "password":
+			conf.Password = flags.Password
+		case "database":
+			conf.Database = flags.Database
+		case "socket":
+			conf.UnixSocket = flags.UnixSocket
+		}
+	})
+	if conf.Driver == "" {
+		conf.Driver = "mysql"
+	}
+
+	e := &env{app: a, conf: conf, stdout: stdout}
+	defer func() {
+		if e.db != nil {
+			e.db.Close()
+		}
+	}()
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			return err
+		}
+		if err != errUsage {
+			fmt.Fprintln(stderr, err)
+		}
+		fmt.Fprintln(stderr, "usage: goloquent [flags] "+cmd.usage)
+		if e.flags != nil {
+			e.flags.SetOutput(stderr)
+			e.flags.PrintDefaults()
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// Main : run the command with `os.Args` and exit the program, the exit code is 2 for invalid usage
+func (a *App) Main() {
+	err := a.Run(context.Background(), os.Args[1:])
+	if err == nil {
+		os.Exit(0)
+	}
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	stderr := a.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	fmt.Fprintln(stderr, err)
+	os.Exit(1)
+}
+
+// flagSet : the flag set of the command, the error and the flags is printed by `Run`
+func (e *env) flagSet(name string) *flag.FlagSet {
+	e.flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	e.flags.SetOutput(ioutil.Discard)
+	return e.flags
+}
+
+func (e *env) parse(args []string) error {
+	if err := e.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errUsage
+		}
+		return fmt.Errorf("%w, %v", errUsage, err)
+	}
+	return nil
+}
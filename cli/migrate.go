@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Oskang09/goloquent"
+)
+
+// migrator : register the Go function migrations of the app and the SQL file migrations of the directory
+func (e *env) migrator(ctx context.Context) (*goloquent.Migrator, error) {
+	fs := e.flags
+	dir := fs.Lookup("dir").Value.String()
+	table := fs.Lookup("table").Value.String()
+	if dir == "" && len(e.app.Migrations) <= 0 {
+		return nil, fmt.Errorf("%w, migration directory is required", errUsage)
+	}
+	conn, err := e.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := goloquent.NewMigrator(conn).SetTable(table)
+	if err := m.Add(e.app.Migrations...); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := m.AddFS(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (e *env) migrationFlags(name string) {
+	fs := e.flagSet(name)
+	fs.String("dir", e.conf.MigrationDir, "directory of the SQL file migrations, \"<version>_<name>.up.sql\" and \"<version>_<name>.down.sql\"")
+	fs.String("table", e.conf.MigrationTable, "name of the migration history table, default is goloquent_migrations")
+}
+
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	e.migrationFlags("migrate")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	fs := e.flags
+	if fs.NArg() <= 0 {
+		return errUsage
+	}
+
+	action, steps := fs.Arg(0), 1
+	switch action {
+	case "up", "unlock":
+		if fs.NArg() > 1 {
+			return errUsage
+		}
+	case "down":
+		if fs.NArg() > 2 {
+			return errUsage
+		}
+		if fs.NArg() == 2 {
+			n, err := strconv.Atoi(fs.Arg(1))
+			if err != nil || n <= 0 {
+				return fmt.Errorf("%w, steps must be a positive number", errUsage)
+			}
+			steps = n
+		}
+	default:
+		return errUsage
+	}
+
+	m, err := e.migrator(ctx)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "up":
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+		for _, mg := range pending {
+			fmt.Fprintf(e.stdout, "applied %s %s\n", mg.Version, mg.Name)
+		}
+		if len(pending) <= 0 {
+			fmt.Fprintln(e.stdout, "no pending migration")
+		}
+	case "down":
+		before, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if err := m.Down(ctx, steps); err != nil {
+			return err
+		}
+		after, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		applied := make(map[string]bool)
+		for _, s := range after {
+			applied[s.Version] = s.Applied
+		}
+		for i := len(before) - 1; i >= 0; i-- {
+			if s := before[i]; s.Applied && !applied[s.Version] {
+				fmt.Fprintf(e.stdout, "reverted %s %s\n", s.Version, s.Name)
+			}
+		}
+	case "unlock":
+		if err := m.Unlock(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(e.stdout, "migration lock is released")
+	}
+	return nil
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	e.migrationFlags("status")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if e.flags.NArg() > 0 {
+		return errUsage
+	}
+	m, err := e.migrator(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range status {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Missing {
+			state = "missing"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Oskang09/goloquent"
+	"github.com/Oskang09/goloquent/generator"
+)
+
+func runGenerate(ctx context.Context, e *env, args []string) error {
+	fs := e.flagSet("generate")
+	pkg := fs.String("package", "model", "package name of the generated source")
+	output := fs.String("o", "", "output file, default is stdout")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+
+	conn, err := e.open(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := generator.Generate(ctx, conn, generator.Options{
+		Package: *pkg,
+		Tables:  fs.Args(),
+	})
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = e.stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(*output, b, 0644)
+}
+
+// runDiff : the changes is printed in the form of "<table>: <action> <subject>", followed by the statements
+func runDiff(ctx context.Context, e *env, args []string) error {
+	fs := e.flagSet("diff")
+	unsafe := fs.Bool("unsafe", false, "include dropping the stale columns and indexes, see `DB.Unsafe`")
+	sqlOnly := fs.Bool("sql", false, "print the statements only")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+	if len(e.app.Models) <= 0 {
+		return fmt.Errorf("goloquent: no model is registered, build the command with `cli.App{Models: ...}`")
+	}
+
+	conn, err := e.open(ctx)
+	if err != nil {
+		return err
+	}
+	if *unsafe {
+		conn = conn.Unsafe()
+	}
+	plan, err := conn.MigratePlan(ctx, e.app.Models...)
+	if err != nil {
+		return err
+	}
+	if *sqlOnly {
+		for _, stmt := range plan.SQL() {
+			fmt.Fprintln(e.stdout, stmt)
+		}
+		return nil
+	}
+
+	isChanged := false
+	for _, tb := range plan.Tables {
+		for _, c := range tb.Changes {
+			isChanged = true
+			fmt.Fprintf(e.stdout, "%s: %s\n", tb.Table, formatChange(c))
+		}
+	}
+	if !isChanged {
+		fmt.Fprintln(e.stdout, "no difference")
+		return nil
+	}
+	if plan.IsDestructive() {
+		fmt.Fprintln(e.stdout, "\nWARNING: the migration may lose the existing data")
+	}
+	if stmts := plan.SQL(); len(stmts) > 0 {
+		fmt.Fprintln(e.stdout, "")
+		for _, stmt := range stmts {
+			fmt.Fprintln(e.stdout, stmt)
+		}
+	}
+	return nil
+}
+
+func formatChange(c goloquent.SchemaChange) string {
+	buf := new(strings.Builder)
+	buf.WriteString(string(c.Action))
+	switch {
+	case c.Index != "":
+		buf.WriteString(" " + c.Index)
+	case c.RenamedFrom != "":
+		buf.WriteString(fmt.Sprintf(" %s -> %s", c.RenamedFrom, c.Column))
+	case c.Column != "":
+		buf.WriteString(" " + c.Column)
+	}
+	switch {
+	case c.From != "" && c.To != "":
+		buf.WriteString(fmt.Sprintf(" (%s -> %s)", c.From, c.To))
+	case c.To != "":
+		buf.WriteString(" (" + c.To + ")")
+	}
+	if c.Destructive {
+		buf.WriteString(" [destructive]")
+	}
+	if c.Skipped {
+		buf.WriteString(" [skipped]")
+	}
+	return buf.String()
+}
+
+func runTables(ctx context.Context, e *env, args []string) error {
+	e.flagSet("tables")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if e.flags.NArg() > 0 {
+		return errUsage
+	}
+	conn, err := e.open(ctx)
+	if err != nil {
+		return err
+	}
+	tables, err := conn.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tb := range tables {
+		fmt.Fprintln(e.stdout, tb)
+	}
+	return nil
+}
+
+// describe : describe the tables in the arguments, the table must exists
+func (e *env) describe(ctx context.Context, args []string, cb func(*tabwriter.Writer, *goloquent.TableInfo)) error {
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if e.flags.NArg() <= 0 {
+		return fmt.Errorf("%w, table is required", errUsage)
+	}
+	conn, err := e.open(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	for i, tb := range e.flags.Args() {
+		info, err := conn.Describe(ctx, tb)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(w, "")
+		}
+		cb(w, info)
+	}
+	return w.Flush()
+}
+
+func writeIndexes(w *tabwriter.Writer, info *goloquent.TableInfo) {
+	fmt.Fprintln(w, "INDEX\tCOLUMNS\tUNIQUE\tPRIMARY\tTYPE")
+	for _, idx := range info.Indexes {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\n", idx.Name, strings.Join(idx.Columns, ", "), idx.IsUnique, idx.IsPrimary, idx.Type)
+	}
+}
+
+func runIndexes(ctx context.Context, e *env, args []string) error {
+	e.flagSet("indexes")
+	return e.describe(ctx, args, func(w *tabwriter.Writer, info *goloquent.TableInfo) {
+		fmt.Fprintf(w, "%s\n", info.Name)
+		writeIndexes(w, info)
+	})
+}
+
+func runDescribe(ctx context.Context, e *env, args []string) error {
+	e.flagSet("describe")
+	return e.describe(ctx, args, func(w *tabwriter.Writer, info *goloquent.TableInfo) {
+		fmt.Fprintf(w, "%s\n", info.Name)
+		fmt.Fprintln(w, "COLUMN\tTYPE\tNULLABLE\tDEFAULT\tCHARSET\tCOLLATION")
+		for _, c := range info.Columns {
+			def := ""
+			if c.Default != nil {
+				def = *c.Default
+			}
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\n", c.Name, c.DataType, c.IsNullable, def, c.Encoding, c.Collation)
+		}
+		if len(info.Indexes) > 0 {
+			fmt.Fprintln(w, "")
+			writeIndexes(w, info)
+		}
+	})
+}
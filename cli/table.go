@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Oskang09/goloquent"
+)
+
+// destroy : run the destructive action on the tables, it require `-force` to prevent the accident,
+// all the tables are checked before any of them is touched
+func (e *env) destroy(ctx context.Context, name string, args []string, cb func(*goloquent.Table) error) error {
+	fs := e.flagSet(name)
+	force := fs.Bool("force", false, "confirm the action, the data of the tables will be lost")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() <= 0 {
+		return fmt.Errorf("%w, table is required", errUsage)
+	}
+	if !*force {
+		return fmt.Errorf("goloquent: %s will lose the data of the tables, rerun with -force to confirm", name)
+	}
+	conn, err := e.open(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tb := range fs.Args() {
+		if !conn.Table(tb).Exists(ctx) {
+			return fmt.Errorf("goloquent: table %q not found", tb)
+		}
+	}
+	for _, tb := range fs.Args() {
+		if err := cb(conn.Table(tb)); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "%s %s\n", name, tb)
+	}
+	return nil
+}
+
+func runTruncate(ctx context.Context, e *env, args []string) error {
+	return e.destroy(ctx, "truncate", args, func(t *goloquent.Table) error {
+		return t.Truncate(ctx)
+	})
+}
+
+func runDrop(ctx context.Context, e *env, args []string) error {
+	return e.destroy(ctx, "drop", args, func(t *goloquent.Table) error {
+		return t.DropIfExists(ctx)
+	})
+}
@@ -0,0 +1,16 @@
+// Command goloquent is the command line tool of goloquent, see package `cli`.
+// It has no model registered, so `diff` always fails, build your own command
+// with `cli.App{Models: ...}` to compare the models against the live schema.
+package main
+
+import (
+	"github.com/Oskang09/goloquent/cli"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	new(cli.App).Main()
+}
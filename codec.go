@@ -0,0 +1,126 @@
+package goloquent
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+var (
+	typeOfValuer  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	typeOfScanner = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// Codec : encode and decode the custom data type, such as decimal, UUID or money,
+// the data type which implement `driver.Valuer` and `sql.Scanner` is supported without registration
+type Codec interface {
+	// DataType : data type of the column, such as "decimal(20,4)", empty string is stored as string,
+	// it can be overridden by the `datatype` tag option
+	DataType() string
+	// Encode : convert the value into driver value, such as nil, int64, float64, bool, []byte, string or time.Time
+	Encode(v reflect.Value) (interface{}, error)
+	// Decode : set the addressable value from the column value, b is nil when the column is NULL
+	Decode(v reflect.Value, b []byte) error
+}
+
+// RegisterCodec : register the codec of the data type in the default registry,
+// it should be registered in `init` before the model is used
+func RegisterCodec(t reflect.Type, c Codec) {
+	defaultRegistry.SetCodec(t, c)
+}
+
+func lookupCodec(t reflect.Type) (Codec, bool) {
+	return defaultRegistry.codec(t)
+}
+
+// codec : the registered codec of the data type, or the codec of `driver.Valuer` and `sql.Scanner`
+func (r *Registry) codec(t reflect.Type) (Codec, bool) {
+	if t == nil {
+		return nil, false
+	}
+	if c, isOk := r.codecs[t]; isOk {
+		return c, true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return nil, false
+	}
+	pt := reflect.PtrTo(t)
+	if (t.Implements(typeOfValuer) || pt.Implements(typeOfValuer)) && pt.Implements(typeOfScanner) {
+		return valuerCodec{}, true
+	}
+	return nil, false
+}
+
+// encodedValue : the driver value encoded by the codec, it's passed to the driver as it is
+type encodedValue struct {
+	value interface{}
+}
+
+// MarshalJSON : the codec value within the slice or struct is stored as json
+func (v encodedValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value)
+}
+
+func encodeCodec(c Codec, v reflect.Value) (encodedValue, error) {
+	it, err := c.Encode(v)
+	if err != nil {
+		return encodedValue{}, fmt.Errorf("goloquent: unable to encode %v, %w", v.Type(), err)
+	}
+	return encodedValue{it}, nil
+}
+
+// decodeCodec : the value within the slice or struct is json, so the string is unquoted
+func decodeCodec(c Codec, t reflect.Type, b []byte, esc bool) (interface{}, error) {
+	if esc && b != nil {
+		if b2s(b) == "null" {
+			b = nil
+		} else if len(b) > 0 && b[0] == '"' {
+			var str string
+			if err := json.Unmarshal(b, &str); err != nil {
+				return nil, err
+			}
+			b = []byte(str)
+		}
+	}
+	v := reflect.New(t).Elem()
+	if err := c.Decode(v, b); err != nil {
+		return nil, fmt.Errorf("goloquent: unable to decode %q to %v, %w", b2s(b), t, err)
+	}
+	return v.Interface(), nil
+}
+
+// valuerCodec : codec of the data type which implement `driver.Valuer` and `sql.Scanner`
+type valuerCodec struct{}
+
+func (valuerCodec) DataType() string {
+	return ""
+}
+
+func (valuerCodec) Encode(v reflect.Value) (interface{}, error) {
+	x, isOk := v.Interface().(driver.Valuer)
+	if !isOk {
+		pv := reflect.New(v.Type())
+		pv.Elem().Set(v)
+		x = pv.Interface().(driver.Valuer)
+	}
+	return x.Value()
+}
+
+func (valuerCodec) Decode(v reflect.Value, b []byte) error {
+	var src interface{}
+	if b != nil {
+		src = b
+	}
+	return v.Addr().Interface().(sql.Scanner).Scan(src)
+}
+
+// codecDataType : the `datatype` tag option take precedence over the data type of the codec
+func codecDataType(f field, c Codec) string {
+	if dt := f.Get("datatype"); dt != "" {
+		return dt
+	}
+	return c.DataType()
+}
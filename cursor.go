@@ -1,19 +1,54 @@
 package goloquent
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"strings"
+	"sync"
 
 	"cloud.google.com/go/datastore"
 )
 
+const cursorDelimeter = "."
+
+// unsignedCursorWarning : warn once when the cursor is accepted without the secret
+var unsignedCursorWarning sync.Once
+
 // Cursor :
 type Cursor struct {
 	cc        []byte
+	mac       []byte
 	Signature string         `json:"signature"`
-	Key       *datastore.Key `json:"next"`
+	Key       *datastore.Key `json:"key"`
+	Values    []interface{}  `json:"values"`
+	Backward  bool           `json:"backward,omitempty"`
+}
+
+// sign the cursor with the secret, the cursor will remain unsigned if the secret is empty
+func (c *Cursor) sign(secret []byte) {
+	c.mac = nil
+	if len(secret) <= 0 || c.cc == nil {
+		return
+	}
+	c.mac = hmacSign(secret, c.cc)
+}
+
+// verify the cursor signature against the secrets, any of the secret is matched consider valid
+func (c Cursor) verify(secrets [][]byte) bool {
+	if len(secrets) <= 0 {
+		return true
+	}
+	if c.mac == nil {
+		return false
+	}
+	for _, s := range secrets {
+		if hmac.Equal(c.mac, hmacSign(s, c.cc)) {
+			return true
+		}
+	}
+	return false
 }
 
 // String :
@@ -21,7 +56,24 @@ func (c Cursor) String() string {
 	if c.cc == nil {
 		return ""
 	}
-	return strings.TrimRight(base64.URLEncoding.EncodeToString(c.cc), "=")
+	str := strings.TrimRight(base64.URLEncoding.EncodeToString(c.cc), "=")
+	if c.mac != nil {
+		str += cursorDelimeter + strings.TrimRight(base64.URLEncoding.EncodeToString(c.mac), "=")
+	}
+	return str
+}
+
+func hmacSign(secret, b []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	if n := len(s) % 4; n != 0 {
+		s += strings.Repeat("=", 4-n)
+	}
+	return base64.URLEncoding.DecodeString(s)
 }
 
 // DecodeCursor :
@@ -29,17 +81,24 @@ func DecodeCursor(c string) (Cursor, error) {
 	if c == "" {
 		return Cursor{}, nil
 	}
-	if n := len(c) % 4; n != 0 {
-		c += strings.Repeat("=", 4-n)
+	paths := strings.Split(c, cursorDelimeter)
+	if len(paths) > 2 {
+		return Cursor{}, ErrInvalidCursor
 	}
-	b, err := base64.URLEncoding.DecodeString(c)
+	b, err := decodeBase64(paths[0])
 	if err != nil {
-		return Cursor{}, fmt.Errorf("goloquent: invalid cursor")
+		return Cursor{}, ErrInvalidCursor
 	}
 	cc := new(Cursor)
 	cc.cc = b
+	if len(paths) > 1 {
+		cc.mac, err = decodeBase64(paths[1])
+		if err != nil || len(cc.mac) <= 0 {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
 	if err := json.Unmarshal(b, cc); err != nil {
-		return Cursor{}, fmt.Errorf("goloquent: invalid cursor")
+		return Cursor{}, ErrInvalidCursor
 	}
 	return *cc, nil
 }
@@ -0,0 +1,40 @@
+package goloquent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestCursor(t *testing.T) {
+	c := Cursor{Signature: "sign", Key: datastore.IDKey("Kind", 100, nil)}
+	c.cc, _ = json.Marshal(c)
+	c.sign([]byte("secret"))
+
+	cc, err := DecodeCursor(c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.Signature != c.Signature || !cc.Key.Equal(c.Key) {
+		t.Errorf(errUnexpectedResult, "DecodeCursor")
+	}
+	if !cc.verify([][]byte{[]byte("secret")}) {
+		t.Errorf(errUnexpectedResult, "verify")
+	}
+	if !cc.verify([][]byte{[]byte("rotated"), []byte("secret")}) {
+		t.Errorf(errUnexpectedResult, "verify")
+	}
+	if cc.verify([][]byte{[]byte("rotated")}) {
+		t.Errorf(errUnexpectedResult, "verify")
+	}
+
+	unsigned := Cursor{Signature: "sign"}
+	unsigned.cc, _ = json.Marshal(unsigned)
+	if unsigned.verify([][]byte{[]byte("secret")}) {
+		t.Errorf(errUnexpectedResult, "verify")
+	}
+	if _, err := DecodeCursor(c.String() + ".abc"); err != ErrInvalidCursor {
+		t.Errorf(errUnexpectedResult, "DecodeCursor")
+	}
+}
@@ -34,6 +34,7 @@ const (
 	pkLen            = 512
 	pkColumn         = "$Key"
 	softDeleteColumn = "$Deleted"
+	parentColumn     = "$Parent"
 	keyDelimeter     = "/"
 )
 
@@ -108,6 +109,11 @@ func (c *Client) compileStmt(query string, args ...interface{}) *Stmt {
 }
 
 func (c Client) execStmt(ctx context.Context, s *stmt) error {
+	_, err := c.execResult(ctx, s)
+	return err
+}
+
+func (c Client) execResult(ctx context.Context, s *stmt) (sql.Result, error) {
 	ss := &Stmt{
 		stmt:     *s,
 		replacer: c.dialect,
@@ -119,10 +125,10 @@ func (c Client) execStmt(ctx context.Context, s *stmt) error {
 	}()
 	result, err := c.PrepareExec(ctx, ss.Raw(), ss.arguments...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	ss.Result = result
-	return nil
+	return result, nil
 }
 
 func (c Client) execQuery(ctx context.Context, s *stmt) (*sql.Rows, error) {
@@ -155,16 +161,32 @@ func (c *Client) execQueryRow(ctx context.Context, s *stmt) *sql.Row {
 	return c.QueryRow(ctx, ss.Raw(), ss.arguments...)
 }
 
+// execCommand : execute the statement without preparing it, such as transaction control statement
+func (c Client) execCommand(ctx context.Context, query string) error {
+	ss := c.compileStmt(query)
+	ss.startTrace()
+	defer func() {
+		ss.stopTrace()
+		c.consoleLog(ctx, ss)
+	}()
+	result, err := c.Exec(ctx, query)
+	if err != nil {
+		return err
+	}
+	ss.Result = result
+	return nil
+}
+
 // PrepareExec :
 func (c Client) PrepareExec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	conn, err := c.sqlCommon.PrepareContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("goloquent: unable to prepare sql statement : %v", err)
+		return nil, fmt.Errorf("goloquent: unable to prepare sql statement : %w", c.dialect.WrapError(err))
 	}
 	defer conn.Close()
 	result, err := conn.Exec(args...)
 	if err != nil {
-		return nil, fmt.Errorf("goloquent: %v", err)
+		return nil, fmt.Errorf("goloquent: %w", c.dialect.WrapError(err))
 	}
 	return result, nil
 }
@@ -173,7 +195,7 @@ func (c Client) PrepareExec(ctx context.Context, query string, args ...interface
 func (c Client) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	result, err := c.sqlCommon.ExecContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("goloquent: %v", err)
+		return nil, fmt.Errorf("goloquent: %w", c.dialect.WrapError(err))
 	}
 	return result, nil
 }
@@ -182,7 +204,7 @@ func (c Client) Exec(ctx context.Context, query string, args ...interface{}) (sq
 func (c Client) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	rows, err := c.sqlCommon.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("goloquent: %v", err)
+		return nil, fmt.Errorf("goloquent: %w", c.dialect.WrapError(err))
 	}
 	return rows, nil
 }
@@ -201,6 +223,15 @@ type DB struct {
 	client  Client
 	dialect Dialect
 	omits   []string
+	// the first secret is use to sign the cursor, the rest is only use to verify the cursor
+	cursorSecrets [][]byte
+	// nested level of the savepoint in transaction
+	savepoint   int
+	retryPolicy *RetryPolicy
+	// allow the migration to drop the stale columns and indexes
+	unsafe bool
+	// the time of the createdAt and updatedAt fields, default is time.Now
+	clock func() time.Time
 }
 
 // NewDB :
@@ -213,6 +244,8 @@ func NewDB(ctx context.Context, driver string, charset CharSet, conn sqlCommon,
 		logger:    logHandler,
 	}
 	dialect.SetDB(client)
+	// the dialect may depend on the server version, such as `ArrayRemove` of mysql
+	dialect.Version(ctx)
 	return &DB{
 		id:      fmt.Sprintf("%s:%d", driver, time.Now().UnixNano()),
 		driver:  driver,
@@ -231,7 +264,57 @@ func (db *DB) clone() *DB {
 		replica: fmt.Sprintf("%d", time.Now().Unix()),
 		client:  db.client,
 		dialect: db.dialect,
+
+		cursorSecrets: db.cursorSecrets,
+		savepoint:     db.savepoint,
+		retryPolicy:   db.retryPolicy,
+		unsafe:        db.unsafe,
+		clock:         db.clock,
+	}
+}
+
+// SetRetryPolicy : re-run the transaction with the policy when it fail with retryable error
+func (db *DB) SetRetryPolicy(p RetryPolicy) {
+	db.retryPolicy = &p
+}
+
+// SetClock : the clock of the createdAt and updatedAt fields, it's useful to freeze the time in test,
+// nil to reset to time.Now
+func (db *DB) SetClock(clock func() time.Time) {
+	db.clock = clock
+}
+
+func (db *DB) now() time.Time {
+	if db.clock != nil {
+		return db.clock().UTC()
 	}
+	return time.Now().UTC()
+}
+
+// SetCursorSecret : sign the pagination cursor using the secret, cursor signed by the previous secrets
+// will still be accepted, so the secret can be rotated without invalidating the issued cursor.
+// Without the secret the cursor is unsigned, it can be forged by the client to seek the query
+// to any value of the sorted fields, so always set the secret if the cursor is exposed
+func (db *DB) SetCursorSecret(secret []byte, previous ...[]byte) {
+	if len(secret) <= 0 {
+		db.cursorSecrets = nil
+		return
+	}
+	secrets := make([][]byte, 0, len(previous)+1)
+	secrets = append(secrets, secret)
+	for _, s := range previous {
+		if len(s) > 0 {
+			secrets = append(secrets, s)
+		}
+	}
+	db.cursorSecrets = secrets
+}
+
+func (db *DB) cursorSecret() []byte {
+	if len(db.cursorSecrets) <= 0 {
+		return nil
+	}
+	return db.cursorSecrets[0]
 }
 
 // ID :
@@ -239,6 +322,11 @@ func (db DB) ID() string {
 	return db.id
 }
 
+// Driver :
+func (db DB) Driver() string {
+	return db.driver
+}
+
 // Name :
 func (db DB) Name() string {
 	return db.name
@@ -269,6 +357,33 @@ func (db *DB) Migrate(ctx context.Context, model ...interface{}) error {
 	return newBuilder(db.NewQuery()).migrateMultiple(ctx, model)
 }
 
+// MigratePlan : dry run of `Migrate`, it return the schema changes and the statements without executing them
+func (db *DB) MigratePlan(ctx context.Context, model ...interface{}) (*MigrationPlan, error) {
+	return newBuilder(db.NewQuery()).migratePlanMultiple(ctx, model)
+}
+
+// Tables : name of the tables in the current database
+func (db *DB) Tables(ctx context.Context) ([]string, error) {
+	tables, err := db.dialect.GetTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("goloquent: %w", db.dialect.WrapError(err))
+	}
+	return tables, nil
+}
+
+// Describe : get the definition of the existing table, such as columns and indexes
+func (db *DB) Describe(ctx context.Context, table string) (*TableInfo, error) {
+	return newBuilder(db.NewQuery()).describe(ctx, table)
+}
+
+// Unsafe : allow `Migrate` to drop the columns and indexes which are not derived from the model,
+// data of the dropped column will be lost, so review it with `MigratePlan` first
+func (db *DB) Unsafe() *DB {
+	clone := db.clone()
+	clone.unsafe = true
+	return clone
+}
+
 // Omit :
 func (db *DB) Omit(fields ...string) Replacer {
 	ff := newDictionary(fields)
@@ -313,6 +428,11 @@ func (db *DB) Destroy(ctx context.Context, model interface{}) error {
 	return newBuilder(db.NewQuery()).delete(ctx, model, false)
 }
 
+// Restore : restore the soft deleted entities
+func (db *DB) Restore(ctx context.Context, model interface{}) error {
+	return newBuilder(db.NewQuery()).restore(ctx, model)
+}
+
 // Truncate :
 func (db *DB) Truncate(ctx context.Context, model ...interface{}) error {
 	ns := make([]string, 0, len(model))
@@ -357,6 +477,11 @@ func (db *DB) Get(ctx context.Context, model interface{}) error {
 	return db.NewQuery().Get(ctx, model)
 }
 
+// Iter :
+func (db *DB) Iter(ctx context.Context, model interface{}) (*StreamIterator, error) {
+	return db.NewQuery().Iter(ctx, model)
+}
+
 // Paginate :
 func (db *DB) Paginate(ctx context.Context, p *Pagination, model interface{}) error {
 	return db.NewQuery().Paginate(ctx, p, model)
@@ -384,7 +509,13 @@ func (db *DB) MatchAgainst(fields []string, value ...string) *Query {
 
 // RunInTransaction :
 func (db *DB) RunInTransaction(cb TransactionHandler) error {
-	return newBuilder(db.NewQuery()).runInTransaction(cb)
+	return db.RunInTransactionCtx(context.Background(), cb, nil)
+}
+
+// RunInTransactionCtx : run the callback in transaction with the options, such as isolation level and read only,
+// calling it with the transactional `*DB` inside the callback will create a savepoint instead
+func (db *DB) RunInTransactionCtx(ctx context.Context, cb TransactionHandler, opts *sql.TxOptions) error {
+	return newBuilder(db.NewQuery()).runInTransaction(ctx, cb, opts)
 }
 
 // Close :
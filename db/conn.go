@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Oskang09/goloquent"
 )
@@ -30,6 +31,14 @@ type Config struct {
 	CharSet    *goloquent.CharSet
 	Logger     goloquent.LogHandler
 	Native     goloquent.NativeHandler
+	// CursorSecrets : the first secret is use to sign the pagination cursor,
+	// the rest is the previous secrets which still accepted during key rotation,
+	// the cursor is unsigned and can be forged by the client if it's empty
+	CursorSecrets [][]byte
+	// RetryPolicy : re-run the transaction when it fail with retryable error, such as deadlock
+	RetryPolicy *goloquent.RetryPolicy
+	// Clock : the time of the createdAt and updatedAt fields, default is time.Now
+	Clock func() time.Time
 }
 
 // Open :
@@ -71,6 +80,15 @@ func Open(ctx context.Context, driver string, conf Config) (*goloquent.DB, error
 	}
 
 	db := goloquent.NewDB(ctx, driver, *config.CharSet, conn, dialect, conf.Logger)
+	if len(conf.CursorSecrets) > 0 {
+		db.SetCursorSecret(conf.CursorSecrets[0], conf.CursorSecrets[1:]...)
+	}
+	if conf.RetryPolicy != nil {
+		db.SetRetryPolicy(*conf.RetryPolicy)
+	}
+	if conf.Clock != nil {
+		db.SetClock(conf.Clock)
+	}
 	pool[conf.Database] = db
 	connPool.Store(driver, pool)
 	// Override defaultDB whenever we initialise a new connection
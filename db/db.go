@@ -51,6 +51,16 @@ func Migrate(ctx context.Context, model ...interface{}) error {
 	return defaultDB.Migrate(ctx, model...)
 }
 
+// MigratePlan :
+func MigratePlan(ctx context.Context, model ...interface{}) (*goloquent.MigrationPlan, error) {
+	return defaultDB.MigratePlan(ctx, model...)
+}
+
+// Describe :
+func Describe(ctx context.Context, table string) (*goloquent.TableInfo, error) {
+	return defaultDB.Describe(ctx, table)
+}
+
 // Omit :
 func Omit(fields ...string) goloquent.Replacer {
 	return defaultDB.Omit(fields...)
@@ -82,6 +92,11 @@ func Destroy(ctx context.Context, model interface{}) error {
 	return defaultDB.Destroy(ctx, model)
 }
 
+// Restore :
+func Restore(ctx context.Context, model interface{}) error {
+	return defaultDB.Restore(ctx, model)
+}
+
 // Save :
 func Save(ctx context.Context, model interface{}) error {
 	return defaultDB.Save(ctx, model)
@@ -132,6 +147,11 @@ func Unscoped() *goloquent.Query {
 	return defaultDB.NewQuery().Unscoped()
 }
 
+// OnlyTrashed :
+func OnlyTrashed() *goloquent.Query {
+	return defaultDB.NewQuery().OnlyTrashed()
+}
+
 // DistinctOn :
 func DistinctOn(fields ...string) *goloquent.Query {
 	return defaultDB.NewQuery().DistinctOn(fields...)
@@ -192,6 +212,16 @@ func RunInTransaction(cb goloquent.TransactionHandler) error {
 	return defaultDB.RunInTransaction(cb)
 }
 
+// NewMigrator :
+func NewMigrator() *goloquent.Migrator {
+	return goloquent.NewMigrator(defaultDB)
+}
+
+// RunInTransactionCtx :
+func RunInTransactionCtx(ctx context.Context, cb goloquent.TransactionHandler, opts *sql.TxOptions) error {
+	return defaultDB.RunInTransactionCtx(ctx, cb, opts)
+}
+
 // Truncate :
 func Truncate(ctx context.Context, model ...interface{}) error {
 	return defaultDB.Truncate(ctx, model...)
@@ -62,6 +62,9 @@ func escape(b []byte) string {
 // []interface{}, *struct
 func valueToInterface(t reflect.Type, v []byte, esc bool) (interface{}, error) {
 	var it interface{}
+	if c, isOk := lookupCodec(t); isOk {
+		return decodeCodec(c, t, v, esc)
+	}
 
 	switch t {
 	case typeOfPtrKey:
@@ -299,6 +302,15 @@ func isZero(v reflect.Value) bool {
 }
 
 func loadField(v reflect.Value, it interface{}) error {
+	if _, isOk := lookupCodec(v.Type()); isOk {
+		x := reflect.ValueOf(it)
+		if !x.IsValid() || x.Type() != v.Type() {
+			return unmatchDataType(v.Interface(), it)
+		}
+		v.Set(x)
+		return nil
+	}
+
 	switch v.Kind() {
 	case reflect.String:
 		x, isOk := it.(string)
@@ -0,0 +1,88 @@
+package goloquent
+
+import (
+	"context"
+	"fmt"
+)
+
+// ColumnInfo : definition of the existing column
+type ColumnInfo struct {
+	Name       string
+	DataType   string
+	IsNullable bool
+	// Default : the default expression of the column, nil if there is no default value
+	Default *string
+	// CharSet : character set and collation of the column, empty if it's not applicable
+	CharSet
+}
+
+// IndexInfo : definition of the existing index
+type IndexInfo struct {
+	Name string
+	// Columns : the indexed columns in order, expression is empty string
+	Columns   []string
+	IsUnique  bool
+	IsPrimary bool
+	// Type : the index method, such as BTREE and FULLTEXT in mysql, btree and gin in postgres
+	Type string
+}
+
+// TableInfo : definition of the existing table
+type TableInfo struct {
+	Name    string
+	Columns []ColumnInfo
+	Indexes []IndexInfo
+}
+
+// Column : get the column by name
+func (t TableInfo) Column(name string) (ColumnInfo, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ColumnInfo{}, false
+}
+
+// Index : get the index by name
+func (t TableInfo) Index(name string) (IndexInfo, bool) {
+	for _, idx := range t.Indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return IndexInfo{}, false
+}
+
+func columnNames(cols []ColumnInfo) []string {
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// appendIndex : the index is fetched by row of each indexed column, so the rows of same index are merged
+func appendIndex(idxs []IndexInfo, idx IndexInfo, col string) []IndexInfo {
+	if n := len(idxs); n > 0 && idxs[n-1].Name == idx.Name {
+		idxs[n-1].Columns = append(idxs[n-1].Columns, col)
+		return idxs
+	}
+	idx.Columns = []string{col}
+	return append(idxs, idx)
+}
+
+func (b *builder) describe(ctx context.Context, table string) (*TableInfo, error) {
+	if !b.db.dialect.HasTable(ctx, table) {
+		return nil, &DriverError{
+			Kind:  ErrTableNotFound,
+			Table: table,
+			Err:   fmt.Errorf("goloquent: table %q not found", table),
+		}
+	}
+	info, err := b.db.dialect.Describe(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("goloquent: %w", b.db.dialect.WrapError(err))
+	}
+	return info, nil
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"reflect"
+
+	"github.com/Oskang09/goloquent/expr"
 )
 
 // Dialect :
@@ -22,13 +24,27 @@ type Dialect interface {
 	GetSchema(c Column) []Schema
 	DataType(s Schema) string
 	HasTable(ctx context.Context, tb string) bool
+	GetTables(ctx context.Context) ([]string, error)
 	HasIndex(ctx context.Context, tb, idx string) bool
 	GetColumns(ctx context.Context, tb string) (cols []string)
 	GetIndexes(ctx context.Context, tb string) (idxs []string)
+	Describe(ctx context.Context, tb string) (*TableInfo, error)
 	CreateTable(ctx context.Context, tb string, cols []Column) error
 	AlterTable(ctx context.Context, tb string, cols []Column, unsafe bool) error
+	PlanCreateTable(ctx context.Context, tb string, cols []Column) (*TablePlan, error)
+	PlanAlterTable(ctx context.Context, tb string, cols []Column, unsafe bool) (*TablePlan, error)
 	OnConflictUpdate(tb string, cols []string) string
+	OnConflictUpdateVersion(tb string, cols []string, version string) string
+	UpdateExpr(col string, u expr.Update, args []interface{}) (string, []interface{}, error)
 	UpdateWithLimit() bool
+	TruncateTable(tb string) string
+	DropIndex(tb, idx string) string
+	LockMode(mode locked) string
+	NullsFirst() bool
+	KeyExpr(col, kind string) string
+	ParentKeyExpr(col string) string
+	WrapError(err error) error
+	IsRetryable(err error) bool
 	ReplaceInto(ctx context.Context, src, dst string) error
 }
 
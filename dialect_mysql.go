@@ -9,13 +9,17 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Oskang09/goloquent/expr"
 	"github.com/Oskang09/goloquent/types"
 )
 
 type mysql struct {
 	sequel
+	// server version, it's read once when the connection is open
+	version string
 }
 
 const minVersion = "5.7"
@@ -48,7 +52,8 @@ func (s *mysql) Open(conf Config) (*sql.DB, error) {
 	if conf.TLSConfig != "" {
 		buf.WriteString("&tls=" + conf.TLSConfig)
 	}
-	log.Println("Connection String :", buf.String())
+	// the password is masked to avoid leaking into the log
+	log.Println("Connection String :", strings.Replace(buf.String(), conf.Username+":"+conf.Password+"@", conf.Username+":****@", 1))
 	client, err := sql.Open("mysql", buf.String())
 	if err != nil {
 		return nil, err
@@ -58,16 +63,35 @@ func (s *mysql) Open(conf Config) (*sql.DB, error) {
 }
 
 // Version :
-func (s mysql) Version(ctx context.Context) (version string) {
-	verRgx := regexp.MustCompile(`(\d\.\d)`)
+func (s *mysql) Version(ctx context.Context) (version string) {
+	if s.version != "" {
+		return s.version
+	}
+
 	s.db.QueryRow(ctx, "SELECT VERSION();").Scan(&version)
 	log.Println("MySQL version :", version)
-	if compareVersion(verRgx.FindStringSubmatch(version)[0], minVersion) > 0 {
+	if compareVersion(semverRgx.FindString(version), minVersion) > 0 {
 		panic(fmt.Errorf("require at least %s version of mysql", minVersion))
 	}
+	s.version = version
 	return
 }
 
+// minJSONTableVersion : JSON_TABLE and the window of JSON_ARRAYAGG which used by `ArrayRemove`
+const minJSONTableVersion = "8.0.14"
+
+var semverRgx = regexp.MustCompile(`^\d+(\.\d+)*`)
+
+// UpdateExpr :
+func (s *mysql) UpdateExpr(col string, u expr.Update, args []interface{}) (string, []interface{}, error) {
+	if u.Op == expr.OpArrayRemove {
+		if compareVersion(semverRgx.FindString(s.version), minJSONTableVersion) > 0 {
+			return "", nil, fmt.Errorf("goloquent: ArrayRemove requires MySQL %s, but got %s", minJSONTableVersion, s.version)
+		}
+	}
+	return s.sequel.UpdateExpr(col, u, args)
+}
+
 // Quote :
 func (s mysql) Quote(n string) string {
 	return "`" + n + "`"
@@ -111,26 +135,60 @@ func (s mysql) OnConflictUpdate(table string, cols []string) string {
 }
 
 func (s mysql) CreateTable(ctx context.Context, table string, columns []Column) error {
+	plan, err := s.PlanCreateTable(ctx, table, columns)
+	if err != nil {
+		return err
+	}
+	return execPlan(ctx, s.db, plan)
+}
+
+// PlanCreateTable :
+func (s mysql) PlanCreateTable(ctx context.Context, table string, columns []Column) (*TablePlan, error) {
+	plan := &TablePlan{Table: table, Create: true}
+	plan.addChange(SchemaChange{Action: ActionCreateTable})
 	buf := new(bytes.Buffer)
 	buf.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", s.GetTable(table)))
 	for _, c := range columns {
 		for _, ss := range s.GetSchema(c) {
 			buf.WriteString(fmt.Sprintf("%s %s,", s.Quote(ss.Name), s.DataType(ss)))
+			plan.addChange(SchemaChange{Action: ActionAddColumn, Column: ss.Name, To: s.columnType(ss)})
 			if ss.IsIndexed || c.field.typeOf == typeOfSoftDelete {
 				idx := fmt.Sprintf("%s_%s_%s", table, ss.Name, "idx")
 				buf.WriteString(fmt.Sprintf("INDEX %s (%s),", s.Quote(idx), s.Quote(ss.Name)))
+				plan.addChange(SchemaChange{Action: ActionAddIndex, Column: ss.Name, Index: idx})
 			}
 		}
 	}
 	buf.WriteString(fmt.Sprintf("PRIMARY KEY (%s)", s.Quote(pkColumn)))
 	buf.WriteString(fmt.Sprintf(") ENGINE=InnoDB DEFAULT CHARSET=%s COLLATE=%s;",
 		s.Quote(s.db.CharSet.Encoding), s.Quote(s.db.CharSet.Collation)))
-	return s.db.execStmt(ctx, &stmt{statement: buf})
+	plan.addStatement(buf)
+	return plan, nil
 }
 
 func (s *mysql) AlterTable(ctx context.Context, table string, columns []Column, unsafe bool) error {
-	cols := types.StringSlice(s.GetColumns(ctx, table))
+	plan, err := s.PlanAlterTable(ctx, table, columns, unsafe)
+	if err != nil {
+		return err
+	}
+	return execPlan(ctx, s.db, plan)
+}
+
+// PlanAlterTable : every column is modified, so the column definition is always in sync with the model,
+// the stale columns and indexes will only be dropped if it's unsafe
+func (s *mysql) PlanAlterTable(ctx context.Context, table string, columns []Column, unsafe bool) (*TablePlan, error) {
+	existing, err := s.describeColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	colTypes := make(map[string]string)
+	for _, c := range existing {
+		colTypes[c.Name] = c.DataType
+	}
+	renames := getRenames(columns, existing)
 	idxs := types.StringSlice(s.GetIndexes(ctx, table))
+	usedCols, usedIdxs := newDictionary(nil), newDictionary(nil)
+	plan := &TablePlan{Table: table}
 
 	var idx string
 	blr := new(bytes.Buffer)
@@ -138,39 +196,86 @@ func (s *mysql) AlterTable(ctx context.Context, table string, columns []Column,
 	suffix := "FIRST"
 	for _, c := range columns {
 		for _, ss := range s.GetSchema(c) {
-			if cols.IndexOf(ss.Name) > -1 {
+			if t, isExist := colTypes[ss.Name]; isExist {
 				blr.WriteString(`MODIFY`)
+				if from, to := s.normalizeType(t), s.columnType(ss); from != to {
+					plan.addChange(SchemaChange{Action: ActionModifyColumn, Column: ss.Name, From: from, To: to, Destructive: true})
+				}
+			} else if old, isOk := renames[ss.Name]; isOk {
+				blr.WriteString(`CHANGE ` + s.Quote(old))
+				plan.addChange(SchemaChange{Action: ActionRenameColumn, Column: ss.Name, RenamedFrom: old})
+				if from, to := s.normalizeType(colTypes[old]), s.columnType(ss); from != to {
+					plan.addChange(SchemaChange{Action: ActionModifyColumn, Column: ss.Name, From: from, To: to, Destructive: true})
+				}
+				usedCols.add(old)
 			} else {
 				blr.WriteString(`ADD`)
+				plan.addChange(SchemaChange{Action: ActionAddColumn, Column: ss.Name, To: s.columnType(ss)})
 			}
+			usedCols.add(ss.Name)
 
 			blr.WriteString(` ` + s.Quote(ss.Name) + ` `)
 			blr.WriteString(s.DataType(ss) + ` ` + suffix)
 			suffix = `AFTER ` + s.Quote(ss.Name)
 
 			if ss.IsIndexed || c.field.typeOf == typeOfSoftDelete {
-				idx = table + `_` + ss.Name + `_idx`
+				idx = s.indexName(table, ss.Name)
+				usedIdxs.add(idx)
 				if idxs.IndexOf(idx) < 0 {
 					blr.WriteRune(',')
 					blr.WriteString(`ADD INDEX ` + s.Quote(idx))
 					blr.WriteString(` (` + s.Quote(ss.Name) + `)`)
+					plan.addChange(SchemaChange{Action: ActionAddIndex, Column: ss.Name, Index: idx})
 				}
 			}
 			blr.WriteRune(',')
 		}
 	}
 
-	// for _, col := range cols.keys() {
-	// 	blr.WriteString(fmt.Sprintf("DROP COLUMN %s,", s.Quote(col)))
-	// }
-	// for _, idx := range idxs.keys() {
-	// 	buf.WriteString(fmt.Sprintf("DROP INDEX %s,", s.Quote(idx)))
-	// }
+	// stale index is dropped before the column, so the index of the dropped column won't be dropped twice
+	staleCols := stale(columnNames(existing), usedCols)
+	for _, idx := range staleIndexes(table, idxs, usedIdxs, staleCols, s.indexName) {
+		plan.addChange(SchemaChange{Action: ActionDropIndex, Index: idx, Skipped: !unsafe})
+		if unsafe {
+			plan.Statements = append(plan.Statements, s.DropIndex(table, idx))
+		}
+	}
+	for _, col := range staleCols {
+		plan.addChange(SchemaChange{Action: ActionDropColumn, Column: col, From: s.normalizeType(colTypes[col]), Destructive: true, Skipped: !unsafe})
+		if unsafe {
+			blr.WriteString(`DROP COLUMN ` + s.Quote(col) + `,`)
+		}
+	}
 
 	blr.WriteString(` CHARACTER SET ` + s.Quote(s.db.CharSet.Encoding))
 	blr.WriteString(` COLLATE ` + s.Quote(s.db.CharSet.Collation))
 	blr.WriteRune(';')
-	return s.db.execStmt(ctx, &stmt{statement: blr})
+	plan.addStatement(blr)
+	return plan, nil
+}
+
+func (s *mysql) indexName(table, col string) string {
+	return table + `_` + col + `_idx`
+}
+
+var intDisplayWidthRgx = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// normalizeType : integer display width is ignored and boolean is the alias of tinyint
+func (s mysql) normalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if t == "boolean" || t == "bool" {
+		return "tinyint"
+	}
+	return intDisplayWidthRgx.ReplaceAllString(t, "$1")
+}
+
+// columnType : the data type of the schema without constraint
+func (s mysql) columnType(sc Schema) string {
+	t := sc.DataType
+	if sc.IsUnsigned {
+		t += " unsigned"
+	}
+	return s.normalizeType(t)
 }
 
 func (s mysql) ToString(it interface{}) string {
@@ -207,6 +312,11 @@ func (s mysql) UpdateWithLimit() bool {
 	return true
 }
 
+// TruncateTable :
+func (s *mysql) TruncateTable(table string) string {
+	return fmt.Sprintf("TRUNCATE TABLE %s;", s.GetTable(table))
+}
+
 func (s mysql) ReplaceInto(ctx context.Context, src, dst string) error {
 	src, dst = s.GetTable(src), s.GetTable(dst)
 	buf := new(bytes.Buffer)
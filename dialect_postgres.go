@@ -5,11 +5,17 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Oskang09/goloquent/expr"
+	"github.com/lib/pq"
 )
 
 type postgres struct {
@@ -30,7 +36,8 @@ func (p postgres) escapeSingleQuote(n string) string {
 func (p *postgres) Open(conf Config) (*sql.DB, error) {
 	buf := new(bytes.Buffer)
 	buf.WriteString(fmt.Sprintf("user='%s' ", p.escapeSingleQuote(conf.Username)))
-	buf.WriteString(fmt.Sprintf("password='%s' ", p.escapeSingleQuote(conf.Password)))
+	password := fmt.Sprintf("password='%s' ", p.escapeSingleQuote(conf.Password))
+	buf.WriteString(password)
 	if conf.UnixSocket != "" {
 		buf.WriteString(fmt.Sprintf("host=/%s ", strings.Trim(conf.UnixSocket, `/`)))
 	} else {
@@ -45,7 +52,8 @@ func (p *postgres) Open(conf Config) (*sql.DB, error) {
 	}
 	buf.WriteString(fmt.Sprintf("dbname='%s' ", p.escapeSingleQuote(conf.Database)))
 	buf.WriteString("sslmode=disable")
-	log.Println("Connection String :", buf.String())
+	// the password is masked to avoid leaking into the log
+	log.Println("Connection String :", strings.Replace(buf.String(), password, "password='****' ", 1))
 	client, err := sql.Open("postgres", buf.String())
 	if err != nil {
 		return nil, err
@@ -230,6 +238,50 @@ func (p postgres) OnConflictUpdate(table string, cols []string) string {
 	return buf.String()
 }
 
+// jsonPath : convert the json path into text array, such as "$.address.lines[0]" to {"address","lines","0"}
+func (p postgres) jsonPath(path string) string {
+	path = strings.TrimPrefix(path, "$.")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	paths := strings.Split(path, ".")
+	for i := range paths {
+		paths[i] = strconv.Quote(paths[i])
+	}
+	return "{" + strings.Join(paths, ",") + "}"
+}
+
+// UpdateExpr :
+func (p postgres) UpdateExpr(col string, u expr.Update, args []interface{}) (string, []interface{}, error) {
+	switch u.Op {
+	case expr.OpIncrement:
+		return fmt.Sprintf("%s + %s", col, variable), args, nil
+	case expr.OpDecrement:
+		return fmt.Sprintf("%s - %s", col, variable), args, nil
+	case expr.OpJSONSet:
+		return fmt.Sprintf("jsonb_set(COALESCE(%s, '{}'::jsonb), %s::text[], %s::jsonb, true)", col, variable, variable),
+			append([]interface{}{p.jsonPath(u.Path)}, args...), nil
+	case expr.OpJSONRemove:
+		return fmt.Sprintf("%s #- %s::text[]", col, variable), []interface{}{p.jsonPath(u.Path)}, nil
+	case expr.OpArrayAppend:
+		return fmt.Sprintf("COALESCE(%s, '[]'::jsonb) || %s::jsonb", col, variable), []interface{}{jsonArray(args)}, nil
+	case expr.OpArrayRemove:
+		return fmt.Sprintf("(SELECT COALESCE(jsonb_agg(x.v ORDER BY x.i), '[]'::jsonb) FROM jsonb_array_elements(%s) WITH ORDINALITY AS x(v, i) "+
+			"WHERE NOT %s::jsonb @> jsonb_build_array(x.v))", col, variable), []interface{}{jsonArray(args)}, nil
+	}
+	return "", nil, fmt.Errorf("goloquent: unsupported update operator %v", u.Op)
+}
+
+// OnConflictUpdateVersion :
+func (p postgres) OnConflictUpdateVersion(table string, cols []string, version string) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET ", p.Quote(pkColumn)))
+	for _, c := range append(cols, version) {
+		buf.WriteString(fmt.Sprintf("%s = EXCLUDED.%s,", p.Quote(c), p.Quote(c)))
+	}
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteString(fmt.Sprintf(" WHERE %s.%s = EXCLUDED.%s - 1", p.GetTable(table), p.Quote(version), p.Quote(version)))
+	return buf.String()
+}
+
 func (p postgres) GetSchema(c Column) []Schema {
 	f := c.field
 	root := f.getRoot()
@@ -260,6 +312,14 @@ func (p postgres) GetSchema(c Column) []Schema {
 		}
 		t = t.Elem()
 	}
+	if c, isOk := lookupCodec(t); isOk {
+		if dt := codecDataType(f, c); dt != "" {
+			sc.DefaultValue = OmitDefault(nil)
+			sc.DataType = dt
+			return []Schema{sc}
+		}
+		t = typeOfString
+	}
 
 	switch t {
 	case typeOfJSONRawMessage:
@@ -361,6 +421,24 @@ func (p *postgres) GetIndexes(ctx context.Context, table string) (idxs []string)
 	return
 }
 
+// GetTables :
+func (p *postgres) GetTables(ctx context.Context) ([]string, error) {
+	rows, err := p.db.Query(ctx, "SELECT table_name FROM INFORMATION_SCHEMA.tables WHERE table_type = 'BASE TABLE' AND table_schema = CURRENT_SCHEMA() ORDER BY table_name;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
 func (p *postgres) HasTable(ctx context.Context, table string) bool {
 	var count int
 	p.db.QueryRow(ctx, "SELECT count(*) FROM INFORMATION_SCHEMA.tables WHERE table_type = 'BASE TABLE' AND table_schema = CURRENT_SCHEMA() AND table_name = $1;", table).Scan(&count)
@@ -401,7 +479,11 @@ func (p *postgres) ToString(it interface{}) string {
 }
 
 func (p *postgres) CreateTable(ctx context.Context, table string, columns []Column) error {
-	idxs := make([]string, 0, len(columns))
+	plan, err := p.PlanCreateTable(ctx, table, columns)
+	if err != nil {
+		return err
+	}
+
 	conn := p.db.sqlCommon.(*sql.DB)
 	tx, err := conn.Begin()
 	if err != nil {
@@ -409,6 +491,33 @@ func (p *postgres) CreateTable(ctx context.Context, table string, columns []Colu
 	}
 	defer tx.Rollback()
 
+	for _, stmt := range plan.Statements {
+		log.Println(stmt)
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p postgres) indexName(table, col string) string {
+	return fmt.Sprintf("%s_%s_%s", table, col, "Idx")
+}
+
+func (p postgres) createIndex(table, col string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("CREATE INDEX %s ON %s (%s);",
+		p.Quote(p.indexName(table, col)), p.GetTable(table), p.Quote(col)))
+	return buf
+}
+
+// PlanCreateTable :
+func (p *postgres) PlanCreateTable(ctx context.Context, table string, columns []Column) (*TablePlan, error) {
+	idxs := make([]*bytes.Buffer, 0, len(columns))
+	plan := &TablePlan{Table: table, Create: true}
+	plan.addChange(SchemaChange{Action: ActionCreateTable})
+
 	buf := new(bytes.Buffer)
 	buf.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", p.GetTable(table)))
 	for _, c := range columns {
@@ -416,39 +525,63 @@ func (p *postgres) CreateTable(ctx context.Context, table string, columns []Colu
 			buf.WriteString(fmt.Sprintf("%s %s,",
 				p.Quote(ss.Name),
 				p.DataType(ss)))
+			plan.addChange(SchemaChange{Action: ActionAddColumn, Column: ss.Name, To: p.normalizeType(ss.DataType)})
 
 			if ss.IsIndexed {
-				idx := fmt.Sprintf("%s_%s_%s", table, ss.Name, "Idx")
-				stmt := fmt.Sprintf("CREATE INDEX %s ON %s (%s);",
-					p.Quote(idx), p.GetTable(table), p.Quote(ss.Name))
-				idxs = append(idxs, stmt)
+				idxs = append(idxs, p.createIndex(table, ss.Name))
+				plan.addChange(SchemaChange{Action: ActionAddIndex, Column: ss.Name, Index: p.indexName(table, ss.Name)})
 			}
 		}
 	}
 	buf.WriteString(fmt.Sprintf("PRIMARY KEY (%s)", p.Quote(pkColumn)))
 	buf.WriteString(");")
-	log.Println(buf.String())
-	if _, err := tx.Exec(buf.String()); err != nil {
-		return err
-	}
-
+	plan.addStatement(buf)
 	for _, idx := range idxs {
-		if _, err := tx.Exec(idx); err != nil {
-			return err
-		}
+		plan.addStatement(idx)
 	}
-
-	return tx.Commit()
+	return plan, nil
 }
 
 func (p *postgres) AlterTable(ctx context.Context, table string, columns []Column, unsafe bool) error {
-	cols := newDictionary(p.GetColumns(ctx, table))
-	idxs := newDictionary(p.GetIndexes(ctx, table))
-	idxs.delete(fmt.Sprintf("%s_pkey", table))
+	plan, err := p.PlanAlterTable(ctx, table, columns, unsafe)
+	if err != nil {
+		return err
+	}
+	return execPlan(ctx, p.db, plan)
+}
+
+// PlanAlterTable : every column is altered, the stale columns and indexes will only be dropped if it's unsafe
+func (p *postgres) PlanAlterTable(ctx context.Context, table string, columns []Column, unsafe bool) (*TablePlan, error) {
+	existing, err := p.describeColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	colTypes := make(map[string]string)
+	for _, c := range existing {
+		colTypes[c.Name] = c.DataType
+	}
+	renames := getRenames(columns, existing)
+	cols := newDictionary(columnNames(existing))
+	idxNames := p.GetIndexes(ctx, table)
+	idxs := newDictionary(idxNames)
+	usedIdxs := newDictionary(nil)
+	newIdxs := make([]*bytes.Buffer, 0)
+	plan := &TablePlan{Table: table}
+
 	buf := new(bytes.Buffer)
 	buf.WriteString(fmt.Sprintf("ALTER TABLE %s ", p.GetTable(table)))
 	for _, c := range columns {
 		for _, ss := range p.GetSchema(c) {
+			// postgres unable to rename the column along with the other actions
+			if old, isOk := renames[ss.Name]; isOk {
+				plan.Statements = append(plan.Statements, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+					p.GetTable(table), p.Quote(old), p.Quote(ss.Name)))
+				plan.addChange(SchemaChange{Action: ActionRenameColumn, Column: ss.Name, RenamedFrom: old})
+				cols.delete(old)
+				cols.add(ss.Name)
+				colTypes[ss.Name] = colTypes[old]
+			}
+
 			if !cols.has(ss.Name) {
 				buf.WriteString(fmt.Sprintf("ADD COLUMN %s %s", p.Quote(ss.Name), ss.DataType))
 				if !ss.IsNullable {
@@ -459,6 +592,7 @@ func (p *postgres) AlterTable(ctx context.Context, table string, columns []Colum
 					}
 				}
 				buf.WriteString(",")
+				plan.addChange(SchemaChange{Action: ActionAddColumn, Column: ss.Name, To: p.normalizeType(ss.DataType)})
 			} else {
 				prefix := fmt.Sprintf("ALTER COLUMN %s", p.Quote(ss.Name))
 				buf.WriteString(fmt.Sprintf("%s TYPE %s", prefix, ss.DataType))
@@ -470,51 +604,256 @@ func (p *postgres) AlterTable(ctx context.Context, table string, columns []Colum
 							prefix, p.ToString(ss.DefaultValue)))
 					}
 				}
+				if from, to := colTypes[ss.Name], p.normalizeType(ss.DataType); from != to {
+					plan.addChange(SchemaChange{Action: ActionModifyColumn, Column: ss.Name, From: from, To: to, Destructive: true})
+				}
 			}
 
 			if ss.IsIndexed {
-				idx := fmt.Sprintf("%s_%s_%s", table, ss.Name, "idx")
-				if idxs.has(idx) {
-					idxs.delete(idx)
-				} else {
-
-					// buf.WriteString(fmt.Sprintf(
-					// 	" CREATE INDEX %s ON (%s);",
-					// 	p.Quote(idx),
-					// 	p.Quote(ss.Name)))
+				idx := p.indexName(table, ss.Name)
+				usedIdxs.add(idx)
+				if !idxs.has(idx) {
+					newIdxs = append(newIdxs, p.createIndex(table, ss.Name))
+					plan.addChange(SchemaChange{Action: ActionAddIndex, Column: ss.Name, Index: idx})
 				}
 			}
 			cols.delete(ss.Name)
 		}
 	}
 
-	for _, col := range cols.keys() {
-		buf.WriteString(fmt.Sprintf(" DROP COLUMN %s,", p.Quote(col)))
+	// stale index is dropped before the column, so the index of the dropped column won't be dropped twice
+	staleCols := make([]string, 0)
+	for _, c := range existing {
+		if cols.has(c.Name) {
+			staleCols = append(staleCols, c.Name)
+		}
+	}
+	for _, idx := range staleIndexes(table, idxNames, usedIdxs, staleCols, p.indexName) {
+		plan.addChange(SchemaChange{Action: ActionDropIndex, Index: idx, Skipped: !unsafe})
+		if unsafe {
+			plan.Statements = append(plan.Statements, p.DropIndex(table, idx))
+		}
+	}
+	for _, c := range existing {
+		if !cols.has(c.Name) {
+			continue
+		}
+		plan.addChange(SchemaChange{Action: ActionDropColumn, Column: c.Name, From: c.DataType, Destructive: true, Skipped: !unsafe})
+		if unsafe {
+			buf.WriteString(fmt.Sprintf(" DROP COLUMN %s,", p.Quote(c.Name)))
+		}
 	}
 
 	buf.Truncate(buf.Len() - 1)
 	buf.WriteString(";")
+	plan.addStatement(buf)
+	for _, idx := range newIdxs {
+		plan.addStatement(idx)
+	}
+	return plan, nil
+}
 
-	log.Println(idxs.keys())
-	return p.db.execStmt(ctx, &stmt{
-		statement: buf,
-	})
+// Describe :
+func (p *postgres) Describe(ctx context.Context, table string) (*TableInfo, error) {
+	cols, err := p.describeColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	idxs, err := p.describeIndexes(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	return &TableInfo{Name: table, Columns: cols, Indexes: idxs}, nil
+}
+
+// describeColumns : character set and collation of the character column is inherited from the database if not specified
+func (p *postgres) describeColumns(ctx context.Context, table string) ([]ColumnInfo, error) {
+	stmt := `SELECT c.column_name, c.data_type, COALESCE(c.character_maximum_length, 0), c.is_nullable, c.column_default,
+	CASE WHEN c.collation_name IS NOT NULL OR c.data_type IN ('character varying', 'character', 'text') THEN pg_encoding_to_char(d.encoding) ELSE '' END,
+	CASE WHEN c.data_type IN ('character varying', 'character', 'text') THEN COALESCE(c.collation_name, d.datcollate::text) ELSE COALESCE(c.collation_name, '') END
+	FROM INFORMATION_SCHEMA.columns c JOIN pg_database d ON d.datname = current_database()
+	WHERE c.table_schema = CURRENT_SCHEMA() AND c.table_name = $1 ORDER BY c.ordinal_position;`
+	rows, err := p.db.Query(ctx, stmt, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols := make([]ColumnInfo, 0)
+	for rows.Next() {
+		var (
+			c          ColumnInfo
+			max        int
+			isNullable string
+			def        sql.NullString
+		)
+		if err := rows.Scan(&c.Name, &c.DataType, &max, &isNullable, &def, &c.Encoding, &c.Collation); err != nil {
+			return nil, err
+		}
+		if max > 0 {
+			c.DataType = fmt.Sprintf("%s(%d)", c.DataType, max)
+		}
+		c.DataType = p.normalizeType(c.DataType)
+		c.IsNullable = isNullable == "YES"
+		if def.Valid {
+			c.Default = &def.String
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+func (p *postgres) describeIndexes(ctx context.Context, table string) ([]IndexInfo, error) {
+	stmt := `SELECT i.relname, COALESCE(a.attname, ''), ix.indisunique, ix.indisprimary, am.amname
+	FROM pg_index ix
+	JOIN pg_class t ON t.oid = ix.indrelid
+	JOIN pg_class i ON i.oid = ix.indexrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	JOIN pg_am am ON am.oid = i.relam
+	JOIN LATERAL unnest(ix.indkey::smallint[]) WITH ORDINALITY AS k(attnum, seq) ON true
+	LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+	WHERE n.nspname = CURRENT_SCHEMA() AND t.relname = $1
+	ORDER BY i.relname, k.seq;`
+	rows, err := p.db.Query(ctx, stmt, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	idxs := make([]IndexInfo, 0)
+	for rows.Next() {
+		var (
+			idx IndexInfo
+			col string
+		)
+		if err := rows.Scan(&idx.Name, &col, &idx.IsUnique, &idx.IsPrimary, &idx.Type); err != nil {
+			return nil, err
+		}
+		idxs = appendIndex(idxs, idx, col)
+	}
+	return idxs, rows.Err()
+}
+
+var pgTypeAliases = map[string]string{
+	"character varying":           "varchar",
+	"character":                   "char",
+	"boolean":                     "bool",
+	"int":                         "integer",
+	"int4":                        "integer",
+	"int2":                        "smallint",
+	"int8":                        "bigint",
+	"float4":                      "real",
+	"float8":                      "double precision",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"decimal":                     "numeric",
+}
+
+var pgTypeRgx = regexp.MustCompile(`^([a-z0-9 ]+?)\s*(\(.*\))?$`)
+
+// normalizeType : the data type of information schema is in its full name, such as "character varying(191)"
+func (p postgres) normalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	result := pgTypeRgx.FindStringSubmatch(t)
+	if result == nil {
+		return t
+	}
+	if alias, isOk := pgTypeAliases[result[1]]; isOk {
+		return alias + result[2]
+	}
+	return t
+}
+
+// DropIndex :
+func (p postgres) DropIndex(table, idx string) string {
+	return fmt.Sprintf("DROP INDEX %s;", p.Quote(idx))
+}
+
+// TruncateTable :
+func (p postgres) TruncateTable(table string) string {
+	return fmt.Sprintf("TRUNCATE TABLE %s;", p.GetTable(table))
+}
+
+// LockMode :
+func (p postgres) LockMode(mode locked) string {
+	switch mode {
+	case ReadLock:
+		return " FOR SHARE"
+	case WriteLock:
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// the patterns to extract the table and column from the postgres error message
+var (
+	pgTableNotFoundRgx  = regexp.MustCompile(`relation "([^"]+)"`)
+	pgColumnNotFoundRgx = regexp.MustCompile(`column "([^"]+)"`)
+)
+
+// WrapError : classify the postgres error by the SQLSTATE code
+func (p postgres) WrapError(err error) error {
+	var e *pq.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	var de *DriverError
+	switch e.Code {
+	case "23505":
+		de = newDriverError(ErrDuplicateKey, err)
+	case "40P01":
+		de = newDriverError(ErrDeadlock, err)
+	case "40001":
+		de = newDriverError(ErrSerialization, err)
+	case "55P03":
+		de = newDriverError(ErrLockTimeout, err)
+	case "23503":
+		de = newDriverError(ErrForeignKey, err)
+	case "23502":
+		de = newDriverError(ErrNotNull, err)
+	case "42P01":
+		de = newDriverError(ErrTableNotFound, err)
+		if m := pgTableNotFoundRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Table = m[1]
+		}
+	case "42703":
+		de = newDriverError(ErrColumnNotFound, err)
+		if m := pgColumnNotFoundRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Column = m[1]
+		}
+	default:
+		return err
+	}
+	if e.Table != "" {
+		de.Table = e.Table
+	}
+	if e.Column != "" {
+		de.Column = e.Column
+	}
+	de.Constraint = e.Constraint
+	return de
+}
+
+// IsRetryable : serialization failure and deadlock can be resolved by re-running the transaction
+func (p postgres) IsRetryable(err error) bool {
+	err = p.WrapError(err)
+	return errors.Is(err, ErrSerialization) || errors.Is(err, ErrDeadlock)
+}
+
+// NullsFirst : NULL is the highest value, which come last in ascending order
+func (p postgres) NullsFirst() bool {
+	return false
+}
+
+// KeyExpr : expression of the full key string of the primary key column, same as the stored key field
+func (p postgres) KeyExpr(col, kind string) string {
+	last := fmt.Sprintf("regexp_replace(%s,'^.*/','')", col)
+	return fmt.Sprintf("(left(%s,length(%s)-length(%s)) || CASE WHEN strpos(%s,',')>0 THEN '' ELSE '%s,' END || %s)",
+		col, col, last, last, kind, last)
+}
 
-	// for _, idx := range idxs.keys() {
-	// 	buff := new(bytes.Buffer)
-	// 	buff.WriteString(fmt.Sprintf("DROP INDEX %s;", p.Quote(idx)))
-	// 	p.db.ConsoleLog(&Stmt{buff, nil, nil})
-	// 	if _, err := tx.Exec(buff.String()); err != nil {
-	// 		return err
-	// 	}
-	// }
-
-	// for _, idx := range idxs.keys() {
-	// 	stmt := fmt.Sprintf("CREATE INDEX %s ON %s ();", p.Quote(idx), p.Quote(table))
-	// 	if _, err := tx.Exec(stmt); err != nil {
-	// 		return err
-	// 	}
-	// }
+// ParentKeyExpr : expression of the parent key string of the primary key column, NULL if it has no parent
+func (p postgres) ParentKeyExpr(col string) string {
+	last := fmt.Sprintf("regexp_replace(%s,'^.*/','')", col)
+	return fmt.Sprintf("NULLIF(rtrim(left(%s,length(%s)-length(%s)),'/'),'')", col, col, last)
 }
 
 func (p *postgres) ReplaceInto(ctx context.Context, src, dst string) error {
@@ -5,11 +5,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Oskang09/goloquent/expr"
+	mysqldriver "github.com/go-sql-driver/mysql"
 )
 
 func checkMultiPtr(v reflect.Value) (isPtr bool, t reflect.Type) {
@@ -295,6 +300,15 @@ func (s *sequel) GetSchema(c Column) []Schema {
 		}
 		t = t.Elem()
 	}
+	if c, isOk := lookupCodec(t); isOk {
+		// the codec without data type is stored as string
+		if dt := codecDataType(f, c); dt != "" {
+			sc.DefaultValue = OmitDefault(nil)
+			sc.DataType = dt
+			return []Schema{sc}
+		}
+		t = typeOfString
+	}
 
 	switch t {
 	case typeOfJSONRawMessage:
@@ -413,6 +427,87 @@ func (s *sequel) GetIndexes(ctx context.Context, table string) (idxs []string) {
 	return
 }
 
+// Describe :
+func (s *sequel) Describe(ctx context.Context, table string) (*TableInfo, error) {
+	cols, err := s.describeColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	idxs, err := s.describeIndexes(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	return &TableInfo{Name: table, Columns: cols, Indexes: idxs}, nil
+}
+
+func (s *sequel) describeColumns(ctx context.Context, table string) ([]ColumnInfo, error) {
+	stmt := "SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, COALESCE(CHARACTER_SET_NAME, ''), COALESCE(COLLATION_NAME, '') FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION;"
+	rows, err := s.db.Query(ctx, stmt, s.CurrentDB(ctx), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols := make([]ColumnInfo, 0)
+	for rows.Next() {
+		var (
+			c          ColumnInfo
+			isNullable string
+			def        sql.NullString
+		)
+		if err := rows.Scan(&c.Name, &c.DataType, &isNullable, &def, &c.Encoding, &c.Collation); err != nil {
+			return nil, err
+		}
+		c.IsNullable = isNullable == "YES"
+		if def.Valid {
+			c.Default = &def.String
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+func (s *sequel) describeIndexes(ctx context.Context, table string) ([]IndexInfo, error) {
+	stmt := "SELECT INDEX_NAME, COLUMN_NAME, NON_UNIQUE, INDEX_TYPE FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY INDEX_NAME, SEQ_IN_INDEX;"
+	rows, err := s.db.Query(ctx, stmt, s.CurrentDB(ctx), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	idxs := make([]IndexInfo, 0)
+	for rows.Next() {
+		var (
+			idx       IndexInfo
+			col       sql.NullString
+			nonUnique int
+		)
+		if err := rows.Scan(&idx.Name, &col, &nonUnique, &idx.Type); err != nil {
+			return nil, err
+		}
+		idx.IsUnique = nonUnique == 0
+		idx.IsPrimary = idx.Name == "PRIMARY"
+		idxs = appendIndex(idxs, idx, col.String)
+	}
+	return idxs, rows.Err()
+}
+
+// GetTables :
+func (s *sequel) GetTables(ctx context.Context) ([]string, error) {
+	rows, err := s.db.Query(ctx, "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME;", s.CurrentDB(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
 func (s *sequel) HasTable(ctx context.Context, table string) bool {
 	var count int
 	s.db.QueryRow(ctx, "SELECT count(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", s.CurrentDB(ctx), table).Scan(&count)
@@ -421,7 +516,7 @@ func (s *sequel) HasTable(ctx context.Context, table string) bool {
 
 func (s *sequel) HasIndex(ctx context.Context, table, idx string) bool {
 	var count int
-	s.db.QueryRow(ctx, "SELECT count(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = ?", s.CurrentDB(ctx), table, idx).Scan(&count)
+	s.db.QueryRow(ctx, "SELECT count(*) FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = ?", s.CurrentDB(ctx), table, idx).Scan(&count)
 	return count > 0
 }
 
@@ -436,6 +531,60 @@ func (s *sequel) OnConflictUpdate(table string, cols []string) string {
 	return buf.String()
 }
 
+// jsonArray : join the json values into json array
+func jsonArray(vals []interface{}) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("[")
+	for i, v := range vals {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(fmt.Sprintf("%s", v))
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// UpdateExpr : col is the column or the nested expression, it must be placed once and before the arguments,
+// the array remove is using `JSON_TABLE`, which requires MySQL 8.0
+func (s *sequel) UpdateExpr(col string, u expr.Update, args []interface{}) (string, []interface{}, error) {
+	switch u.Op {
+	case expr.OpIncrement:
+		return fmt.Sprintf("%s + %s", col, variable), args, nil
+	case expr.OpDecrement:
+		return fmt.Sprintf("%s - %s", col, variable), args, nil
+	case expr.OpJSONSet:
+		return fmt.Sprintf("JSON_SET(COALESCE(%s, JSON_OBJECT()), %s, CAST(%s AS JSON))", col, variable, variable),
+			append([]interface{}{u.Path}, args...), nil
+	case expr.OpJSONRemove:
+		return fmt.Sprintf("JSON_REMOVE(%s, %s)", col, variable), []interface{}{u.Path}, nil
+	case expr.OpArrayAppend:
+		return fmt.Sprintf("JSON_MERGE_PRESERVE(COALESCE(%s, JSON_ARRAY()), CAST(%s AS JSON))", col, variable),
+			[]interface{}{jsonArray(args)}, nil
+	case expr.OpArrayRemove:
+		// JSON_ARRAYAGG has no ORDER BY, so the window keeps the elements in the original order
+		elems := fmt.Sprintf("JSON_TABLE(%s, '$[*]' COLUMNS (i FOR ORDINALITY, v JSON PATH '$')) AS x", col)
+		agg := "JSON_ARRAYAGG(x.v) OVER (ORDER BY x.i ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)"
+		return fmt.Sprintf("COALESCE((SELECT %s FROM %s WHERE NOT JSON_CONTAINS(CAST(%s AS JSON), x.v) LIMIT 1), JSON_ARRAY())", agg, elems, variable),
+			[]interface{}{jsonArray(args)}, nil
+	}
+	return "", nil, fmt.Errorf("goloquent: unsupported update operator %v", u.Op)
+}
+
+// OnConflictUpdateVersion : the columns is only updated when the version of the record is the previous version,
+// the version column is updated at last, as the assignment is evaluated from left to right
+func (s *sequel) OnConflictUpdateVersion(table string, cols []string, version string) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("ON DUPLICATE KEY UPDATE ")
+	v := s.Quote(version)
+	for _, c := range append(cols, version) {
+		c = s.Quote(c)
+		buf.WriteString(fmt.Sprintf("%s=IF(%s=VALUES(%s)-1,VALUES(%s),%s),", c, v, v, c, c))
+	}
+	buf.Truncate(buf.Len() - 1)
+	return buf.String()
+}
+
 func (s *sequel) CreateTable(context.Context, string, []Column) error {
 	return nil
 }
@@ -444,10 +593,114 @@ func (s *sequel) AlterTable(context.Context, string, []Column, bool) error {
 	return nil
 }
 
+func (s *sequel) PlanCreateTable(ctx context.Context, table string, columns []Column) (*TablePlan, error) {
+	return &TablePlan{Table: table, Create: true}, nil
+}
+
+func (s *sequel) PlanAlterTable(ctx context.Context, table string, columns []Column, unsafe bool) (*TablePlan, error) {
+	return &TablePlan{Table: table}, nil
+}
+
 func (s sequel) UpdateWithLimit() bool {
 	return false
 }
 
+// DropIndex :
+func (s *sequel) DropIndex(table, idx string) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s;", s.Quote(idx), s.GetTable(table))
+}
+
+// TruncateTable :
+func (s *sequel) TruncateTable(table string) string {
+	return fmt.Sprintf("TRUNCATE TABLE %s;", s.GetTable(table))
+}
+
+// LockMode :
+func (s sequel) LockMode(mode locked) string {
+	switch mode {
+	case ReadLock:
+		return " LOCK IN SHARE MODE"
+	case WriteLock:
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// NullsFirst : NULL is the lowest value, which come first in ascending order
+func (s sequel) NullsFirst() bool {
+	return true
+}
+
+// the patterns to extract the table and column from the mysql error message
+var (
+	mysqlDuplicateKeyRgx   = regexp.MustCompile("for key '(?:(.+)\\.)?([^'.]+)'")
+	mysqlForeignKeyRgx     = regexp.MustCompile("`([^`]+)`, CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`")
+	mysqlNotNullRgx        = regexp.MustCompile("Column '([^']+)'")
+	mysqlTableNotFoundRgx  = regexp.MustCompile("Table '(?:[^'.]+\\.)?([^']+)'")
+	mysqlColumnNotFoundRgx = regexp.MustCompile("Unknown column '([^']+)'")
+)
+
+// WrapError : classify the mysql error by the error number, the table and column is extracted from the error message
+func (s sequel) WrapError(err error) error {
+	var e *mysqldriver.MySQLError
+	if !errors.As(err, &e) {
+		return err
+	}
+	var de *DriverError
+	switch e.Number {
+	case 1062:
+		de = newDriverError(ErrDuplicateKey, err)
+		if m := mysqlDuplicateKeyRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Table, de.Constraint = m[1], m[2]
+		}
+	case 1213:
+		de = newDriverError(ErrDeadlock, err)
+	case 1205:
+		de = newDriverError(ErrLockTimeout, err)
+	case 1216, 1217, 1451, 1452:
+		de = newDriverError(ErrForeignKey, err)
+		if m := mysqlForeignKeyRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Table, de.Constraint, de.Column = m[1], m[2], m[3]
+		}
+	case 1048:
+		de = newDriverError(ErrNotNull, err)
+		if m := mysqlNotNullRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Column = m[1]
+		}
+	case 1146:
+		de = newDriverError(ErrTableNotFound, err)
+		if m := mysqlTableNotFoundRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Table = m[1]
+		}
+	case 1054:
+		de = newDriverError(ErrColumnNotFound, err)
+		if m := mysqlColumnNotFoundRgx.FindStringSubmatch(e.Message); m != nil {
+			de.Column = m[1]
+		}
+	default:
+		return err
+	}
+	return de
+}
+
+// IsRetryable : deadlock can be resolved by re-running the transaction
+func (s sequel) IsRetryable(err error) bool {
+	return errors.Is(s.WrapError(err), ErrDeadlock)
+}
+
+// KeyExpr : expression of the full key string of the primary key column, same as the stored key field
+func (s sequel) KeyExpr(col, kind string) string {
+	last := fmt.Sprintf("SUBSTRING_INDEX(%s,'/',-1)", col)
+	return fmt.Sprintf("CONCAT(LEFT(%s,CHAR_LENGTH(%s)-CHAR_LENGTH(%s)),IF(LOCATE(',',%s)>0,'','%s,'),%s)",
+		col, col, last, last, kind, last)
+}
+
+// ParentKeyExpr : expression of the parent key string of the primary key column, NULL if it has no parent
+func (s sequel) ParentKeyExpr(col string) string {
+	last := fmt.Sprintf("SUBSTRING_INDEX(%s,'/',-1)", col)
+	return fmt.Sprintf("NULLIF(TRIM(TRAILING '/' FROM LEFT(%s,CHAR_LENGTH(%s)-CHAR_LENGTH(%s))),'')", col, col, last)
+}
+
 func (s sequel) ReplaceInto(ctx context.Context, src, dst string) error {
 	return nil
 }
@@ -0,0 +1,711 @@
+package goloquent
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Oskang09/goloquent/expr"
+	"github.com/Oskang09/goloquent/types"
+)
+
+type sqlite struct {
+	sequel
+}
+
+const memoryDB = ":memory:"
+
+var _ Dialect = new(sqlite)
+
+func init() {
+	RegisterDialect("sqlite", new(sqlite))
+}
+
+// Open : the database name is the file path of the database,
+// it will fallback to in-memory database when the name is empty
+func (s *sqlite) Open(conf Config) (*sql.DB, error) {
+	driver := "sqlite3"
+	drivers := types.StringSlice(sql.Drivers())
+	if drivers.IndexOf(driver) < 0 && drivers.IndexOf("sqlite") > -1 {
+		driver = "sqlite"
+	}
+	dsn := conf.Database
+	if dsn == "" {
+		dsn = memoryDB
+	}
+	client, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dsn == memoryDB {
+		// every connection of in-memory database is a new database,
+		// so we must stick to the same connection
+		client.SetMaxOpenConns(1)
+	}
+	return client, nil
+}
+
+// GetTable :
+func (s sqlite) GetTable(name string) string {
+	return s.Quote(name)
+}
+
+// Version :
+func (s *sqlite) Version(ctx context.Context) (version string) {
+	s.db.QueryRow(ctx, "SELECT sqlite_version();").Scan(&version)
+	return
+}
+
+// CurrentDB :
+func (s *sqlite) CurrentDB(ctx context.Context) (name string) {
+	if s.dbName == "" {
+		s.dbName = "main"
+	}
+	return s.dbName
+}
+
+// Quote :
+func (s sqlite) Quote(n string) string {
+	return fmt.Sprintf(`"%s"`, n)
+}
+
+// Bind :
+func (s sqlite) Bind(uint) string {
+	return "?"
+}
+
+func (s sqlite) splitJSON(name string) (string, string) {
+	paths := strings.SplitN(name, ">", 2)
+	if len(paths) <= 1 {
+		return s.Quote(strings.TrimSpace(paths[0])), "$"
+	}
+	return s.Quote(strings.TrimSpace(paths[0])), "$." + strings.TrimSpace(paths[1])
+}
+
+// SplitJSON :
+func (s sqlite) SplitJSON(name string) string {
+	col, path := s.splitJSON(name)
+	if path == "$" {
+		return col
+	}
+	return fmt.Sprintf("json_extract(%s, %s)", col, s.Value(path))
+}
+
+// jsonValue convert the value to sql value which able to compare with the output of `json_extract`
+func (s sqlite) jsonValue(it interface{}) interface{} {
+	switch vi := it.(type) {
+	case json.RawMessage:
+		return string(vi)
+	case bool:
+		if vi {
+			return 1
+		}
+		return 0
+	}
+	return it
+}
+
+func (s sqlite) FilterJSON(f Filter) (string, []interface{}, error) {
+	vv, err := f.Interface()
+	if err != nil {
+		return "", nil, err
+	}
+	name := s.SplitJSON(f.Field())
+	buf, args := new(bytes.Buffer), make([]interface{}, 0)
+	switch f.operator {
+	case Equal:
+		if vv == nil {
+			buf.WriteString(fmt.Sprintf("(%s) IS NULL", name))
+			return buf.String(), args, nil
+		}
+		buf.WriteString(fmt.Sprintf("(%s) = %s", name, variable))
+	case NotEqual:
+		if vv == nil {
+			buf.WriteString(fmt.Sprintf("(%s) IS NOT NULL", name))
+			return buf.String(), args, nil
+		}
+		buf.WriteString(fmt.Sprintf("(%s) <> %s", name, variable))
+	case GreaterThan:
+		buf.WriteString(fmt.Sprintf("(%s) > %s", name, variable))
+	case GreaterEqual:
+		buf.WriteString(fmt.Sprintf("(%s) >= %s", name, variable))
+	case LessThan:
+		buf.WriteString(fmt.Sprintf("(%s) < %s", name, variable))
+	case LessEqual:
+		buf.WriteString(fmt.Sprintf("(%s) <= %s", name, variable))
+	case In, NotIn:
+		x, isOk := vv.([]interface{})
+		if !isOk {
+			x = append(x, vv)
+		}
+		if len(x) <= 0 {
+			return "", nil, fmt.Errorf(`goloquent: value for "In" operator cannot be empty`)
+		}
+		op := "IN"
+		if f.operator == NotIn {
+			op = "NOT IN"
+		}
+		buf.WriteString(fmt.Sprintf("(%s) %s (", name, op))
+		for i := 0; i < len(x); i++ {
+			buf.WriteString(variable + ",")
+			args = append(args, s.jsonValue(x[i]))
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteString(")")
+		return buf.String(), args, nil
+	case ContainAny:
+		x, isOk := vv.([]interface{})
+		if !isOk {
+			x = append(x, vv)
+		}
+		if len(x) <= 0 {
+			return "", nil, fmt.Errorf(`goloquent: value for "ContainAny" operator cannot be empty`)
+		}
+		col, path := s.splitJSON(f.Field())
+		buf.WriteString(fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s, %s) WHERE json_each.value IN (", col, s.Value(path)))
+		for i := 0; i < len(x); i++ {
+			buf.WriteString(variable + ",")
+			args = append(args, s.jsonValue(x[i]))
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteString("))")
+		return buf.String(), args, nil
+	case IsType:
+		col, path := s.splitJSON(f.Field())
+		typ := strings.ToLower(fmt.Sprintf("%v", vv))
+		switch typ {
+		case "string":
+			typ = "text"
+		case "double", "float":
+			typ = "real"
+		case "boolean", "bool":
+			buf.WriteString(fmt.Sprintf("json_type(%s, %s) IN ('true','false')", col, s.Value(path)))
+			return buf.String(), args, nil
+		}
+		buf.WriteString(fmt.Sprintf("json_type(%s, %s) = %s", col, s.Value(path), variable))
+		args = append(args, typ)
+		return buf.String(), args, nil
+	case IsObject:
+		col, path := s.splitJSON(f.Field())
+		buf.WriteString(fmt.Sprintf("json_type(%s, %s) = 'object'", col, s.Value(path)))
+		return buf.String(), args, nil
+	case IsArray:
+		col, path := s.splitJSON(f.Field())
+		buf.WriteString(fmt.Sprintf("json_type(%s, %s) = 'array'", col, s.Value(path)))
+		return buf.String(), args, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operator")
+	}
+
+	args = append(args, s.jsonValue(vv))
+	return buf.String(), args, nil
+}
+
+// Value :
+func (s sqlite) Value(it interface{}) string {
+	var str string
+	switch vi := it.(type) {
+	case nil:
+		str = "NULL"
+	case json.RawMessage:
+		str = fmt.Sprintf(`'%s'`, escapeSingleQuote(fmt.Sprintf(`%s`, vi)))
+	case string, []byte:
+		str = fmt.Sprintf(`'%s'`, escapeSingleQuote(fmt.Sprintf(`%s`, vi)))
+	default:
+		str = fmt.Sprintf("%v", vi)
+	}
+	return str
+}
+
+// GetSchema : sqlite only have storage class, so the data type is just for type affinity
+func (s *sqlite) GetSchema(c Column) []Schema {
+	schemas := s.sequel.GetSchema(c)
+	for i := range schemas {
+		schemas[i].CharSet = CharSet{}
+	}
+	return schemas
+}
+
+// DataType :
+func (s sqlite) DataType(sc Schema) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(sc.DataType)
+	if sc.IsUnsigned {
+		buf.WriteString(fmt.Sprintf(" CHECK (%s >= 0)", s.Quote(sc.Name)))
+	}
+	if !sc.IsNullable {
+		buf.WriteString(" NOT NULL")
+		if !sc.IsOmitEmpty() {
+			buf.WriteString(fmt.Sprintf(" DEFAULT %s", s.ToString(sc.DefaultValue)))
+		}
+	}
+	return buf.String()
+}
+
+// ToString :
+func (s sqlite) ToString(it interface{}) string {
+	var v string
+	switch vi := it.(type) {
+	case nil:
+		v = "NULL"
+	case string:
+		v = fmt.Sprintf(`'%s'`, escapeSingleQuote(vi))
+	case bool:
+		v = "0"
+		if vi {
+			v = "1"
+		}
+	case uint, uint8, uint16, uint32, uint64:
+		v = fmt.Sprintf("%d", vi)
+	case int, int8, int16, int32, int64:
+		v = fmt.Sprintf("%d", vi)
+	case float32, float64:
+		v = fmt.Sprintf("%v", vi)
+	case time.Time:
+		v = fmt.Sprintf(`'%s'`, vi.Format("2006-01-02 15:04:05"))
+	case []interface{}:
+		v = fmt.Sprintf(`'%s'`, "[]")
+	case map[string]interface{}:
+		v = fmt.Sprintf(`'%s'`, "{}")
+	default:
+		v = fmt.Sprintf("%v", vi)
+	}
+	return v
+}
+
+// OnConflictUpdate :
+func (s sqlite) OnConflictUpdate(table string, cols []string) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET ", s.Quote(pkColumn)))
+	for _, c := range cols {
+		buf.WriteString(fmt.Sprintf("%s = excluded.%s,", s.Quote(c), s.Quote(c)))
+	}
+	buf.Truncate(buf.Len() - 1)
+	return buf.String()
+}
+
+// jsonElement : the json text of the element of `json_each`, the boolean is converted to integer by sqlite
+func (s sqlite) jsonElement(alias string) string {
+	return fmt.Sprintf("CASE %s.type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' WHEN 'null' THEN 'null' "+
+		"WHEN 'object' THEN %s.value WHEN 'array' THEN %s.value ELSE json_quote(%s.value) END", alias, alias, alias, alias)
+}
+
+// UpdateExpr :
+func (s sqlite) UpdateExpr(col string, u expr.Update, args []interface{}) (string, []interface{}, error) {
+	switch u.Op {
+	case expr.OpIncrement:
+		return fmt.Sprintf("%s + %s", col, variable), args, nil
+	case expr.OpDecrement:
+		return fmt.Sprintf("%s - %s", col, variable), args, nil
+	case expr.OpJSONSet:
+		return fmt.Sprintf("json_set(COALESCE(%s, '{}'), %s, json(%s))", col, variable, variable),
+			append([]interface{}{u.Path}, args...), nil
+	case expr.OpJSONRemove:
+		return fmt.Sprintf("json_remove(%s, %s)", col, variable), []interface{}{u.Path}, nil
+	case expr.OpArrayAppend:
+		buf := new(bytes.Buffer)
+		buf.WriteString(fmt.Sprintf("json_insert(COALESCE(%s, '[]')", col))
+		for range args {
+			buf.WriteString(fmt.Sprintf(", '$[#]', json(%s)", variable))
+		}
+		buf.WriteString(")")
+		return buf.String(), args, nil
+	case expr.OpArrayRemove:
+		return fmt.Sprintf("(SELECT json_group_array(json(%s)) FROM json_each(%s) AS x WHERE %s NOT IN (SELECT %s FROM json_each(%s) AS y))",
+			s.jsonElement("x"), col, s.jsonElement("x"), s.jsonElement("y"), variable), []interface{}{jsonArray(args)}, nil
+	}
+	return "", nil, fmt.Errorf("goloquent: unsupported update operator %v", u.Op)
+}
+
+// OnConflictUpdateVersion :
+func (s sqlite) OnConflictUpdateVersion(table string, cols []string, version string) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET ", s.Quote(pkColumn)))
+	for _, c := range append(cols, version) {
+		buf.WriteString(fmt.Sprintf("%s = excluded.%s,", s.Quote(c), s.Quote(c)))
+	}
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteString(fmt.Sprintf(" WHERE %s.%s = excluded.%s - 1", s.GetTable(table), s.Quote(version), s.Quote(version)))
+	return buf.String()
+}
+
+func (s *sqlite) indexName(table, col string) string {
+	return fmt.Sprintf("%s_%s_%s", table, col, "idx")
+}
+
+func (s *sqlite) createIndex(table, col string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s);",
+		s.Quote(s.indexName(table, col)), s.GetTable(table), s.Quote(col)))
+	return buf
+}
+
+// CreateTable :
+func (s *sqlite) CreateTable(ctx context.Context, table string, columns []Column) error {
+	plan, err := s.PlanCreateTable(ctx, table, columns)
+	if err != nil {
+		return err
+	}
+	return execPlan(ctx, s.db, plan)
+}
+
+// PlanCreateTable :
+func (s *sqlite) PlanCreateTable(ctx context.Context, table string, columns []Column) (*TablePlan, error) {
+	idxs := make([]*bytes.Buffer, 0, len(columns))
+	plan := &TablePlan{Table: table, Create: true}
+	plan.addChange(SchemaChange{Action: ActionCreateTable})
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", s.GetTable(table)))
+	for _, c := range columns {
+		for _, ss := range s.GetSchema(c) {
+			buf.WriteString(fmt.Sprintf("%s %s,", s.Quote(ss.Name), s.DataType(ss)))
+			plan.addChange(SchemaChange{Action: ActionAddColumn, Column: ss.Name, To: s.normalizeType(ss.DataType)})
+			if ss.IsIndexed {
+				idxs = append(idxs, s.createIndex(table, ss.Name))
+				plan.addChange(SchemaChange{Action: ActionAddIndex, Column: ss.Name, Index: s.indexName(table, ss.Name)})
+			}
+		}
+	}
+	buf.WriteString(fmt.Sprintf("PRIMARY KEY (%s)", s.Quote(pkColumn)))
+	buf.WriteString(");")
+	plan.addStatement(buf)
+	for _, idx := range idxs {
+		plan.addStatement(idx)
+	}
+	return plan, nil
+}
+
+// AlterTable : sqlite unable to modify the existing column, so only new column and index will be added
+func (s *sqlite) AlterTable(ctx context.Context, table string, columns []Column, unsafe bool) error {
+	plan, err := s.PlanAlterTable(ctx, table, columns, unsafe)
+	if err != nil {
+		return err
+	}
+	return execPlan(ctx, s.db, plan)
+}
+
+// PlanAlterTable : the type change of the existing column is reported but skipped,
+// the stale columns and indexes will only be dropped if it's unsafe
+func (s *sqlite) PlanAlterTable(ctx context.Context, table string, columns []Column, unsafe bool) (*TablePlan, error) {
+	existing, err := s.describeColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	colTypes := make(map[string]string)
+	for _, c := range existing {
+		colTypes[c.Name] = c.DataType
+	}
+	renames := getRenames(columns, existing)
+	idxNames := s.GetIndexes(ctx, table)
+	idxs := newDictionary(idxNames)
+	usedCols, usedIdxs := newDictionary(nil), newDictionary(nil)
+	newIdxs := make([]*bytes.Buffer, 0)
+	plan := &TablePlan{Table: table}
+	for _, c := range columns {
+		for _, ss := range s.GetSchema(c) {
+			usedCols.add(ss.Name)
+			if old, isOk := renames[ss.Name]; isOk {
+				buf := new(bytes.Buffer)
+				buf.WriteString(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+					s.GetTable(table), s.Quote(old), s.Quote(ss.Name)))
+				plan.addStatement(buf)
+				plan.addChange(SchemaChange{Action: ActionRenameColumn, Column: ss.Name, RenamedFrom: old})
+				usedCols.add(old)
+				colTypes[ss.Name] = colTypes[old]
+			}
+
+			if t, isExist := colTypes[ss.Name]; !isExist {
+				// sqlite doesn't allow adding not null column without default value
+				if !ss.IsNullable && ss.IsOmitEmpty() {
+					ss.IsNullable = true
+				}
+				buf := new(bytes.Buffer)
+				buf.WriteString(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
+					s.GetTable(table), s.Quote(ss.Name), s.DataType(ss)))
+				plan.addStatement(buf)
+				plan.addChange(SchemaChange{Action: ActionAddColumn, Column: ss.Name, To: s.normalizeType(ss.DataType)})
+			} else if from, to := s.normalizeType(t), s.normalizeType(ss.DataType); from != to {
+				plan.addChange(SchemaChange{Action: ActionModifyColumn, Column: ss.Name, From: from, To: to, Destructive: true, Skipped: true})
+			}
+
+			if !ss.IsIndexed {
+				continue
+			}
+			idx := s.indexName(table, ss.Name)
+			usedIdxs.add(idx)
+			if !idxs.has(idx) {
+				newIdxs = append(newIdxs, s.createIndex(table, ss.Name))
+				plan.addChange(SchemaChange{Action: ActionAddIndex, Column: ss.Name, Index: idx})
+			}
+		}
+	}
+
+	// sqlite unable to drop the indexed column, so the stale index is dropped first
+	staleCols := stale(columnNames(existing), usedCols)
+	for _, idx := range staleIndexes(table, idxNames, usedIdxs, staleCols, s.indexName) {
+		plan.addChange(SchemaChange{Action: ActionDropIndex, Index: idx, Skipped: !unsafe})
+		if unsafe {
+			plan.Statements = append(plan.Statements, s.DropIndex(table, idx))
+		}
+	}
+	for _, col := range staleCols {
+		plan.addChange(SchemaChange{Action: ActionDropColumn, Column: col, From: s.normalizeType(colTypes[col]), Destructive: true, Skipped: !unsafe})
+		if unsafe {
+			plan.Statements = append(plan.Statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;",
+				s.GetTable(table), s.Quote(col)))
+		}
+	}
+	for _, idx := range newIdxs {
+		plan.addStatement(idx)
+	}
+	return plan, nil
+}
+
+// Describe :
+func (s *sqlite) Describe(ctx context.Context, table string) (*TableInfo, error) {
+	cols, err := s.describeColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	idxs, err := s.describeIndexes(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	return &TableInfo{Name: table, Columns: cols, Indexes: idxs}, nil
+}
+
+// describeColumns : sqlite doesn't have character set and collation is not reported by pragma
+func (s *sqlite) describeColumns(ctx context.Context, table string) ([]ColumnInfo, error) {
+	rows, err := s.db.Query(ctx, `SELECT name, type, "notnull", dflt_value FROM pragma_table_info(?);`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols := make([]ColumnInfo, 0)
+	for rows.Next() {
+		var (
+			c       ColumnInfo
+			notNull bool
+			def     sql.NullString
+		)
+		if err := rows.Scan(&c.Name, &c.DataType, &notNull, &def); err != nil {
+			return nil, err
+		}
+		c.IsNullable = !notNull
+		if def.Valid {
+			c.Default = &def.String
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// describeIndexes : the index list is read before the index info, as the connection is not shared by the open rows
+func (s *sqlite) describeIndexes(ctx context.Context, table string) ([]IndexInfo, error) {
+	rows, err := s.db.Query(ctx, `SELECT name, "unique", origin FROM pragma_index_list(?) ORDER BY name;`, table)
+	if err != nil {
+		return nil, err
+	}
+	idxs := make([]IndexInfo, 0)
+	for rows.Next() {
+		var (
+			idx    IndexInfo
+			origin string
+		)
+		if err := rows.Scan(&idx.Name, &idx.IsUnique, &origin); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		idx.IsPrimary = origin == "pk"
+		idx.Type = "btree"
+		idxs = append(idxs, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range idxs {
+		rows, err := s.db.Query(ctx, "SELECT COALESCE(name, '') FROM pragma_index_info(?) ORDER BY seqno;", idxs[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		idxs[i].Columns = make([]string, 0)
+		for rows.Next() {
+			var col string
+			if err := rows.Scan(&col); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			idxs[i].Columns = append(idxs[i].Columns, col)
+		}
+		rows.Close()
+	}
+	return idxs, nil
+}
+
+// normalizeType : sqlite keep the declared data type as it is
+func (s sqlite) normalizeType(t string) string {
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// GetColumns :
+func (s *sqlite) GetColumns(ctx context.Context, table string) (columns []string) {
+	stmt := "SELECT name FROM pragma_table_info(?);"
+	rows, err := s.db.Query(ctx, stmt, table)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for i := 0; rows.Next(); i++ {
+		columns = append(columns, "")
+		rows.Scan(&columns[i])
+	}
+	return
+}
+
+// GetIndexes :
+func (s *sqlite) GetIndexes(ctx context.Context, table string) (idxs []string) {
+	stmt := "SELECT name FROM pragma_index_list(?) WHERE origin = 'c';"
+	rows, err := s.db.Query(ctx, stmt, table)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for i := 0; rows.Next(); i++ {
+		idxs = append(idxs, "")
+		rows.Scan(&idxs[i])
+	}
+	return
+}
+
+// GetTables :
+func (s *sqlite) GetTables(ctx context.Context) ([]string, error) {
+	rows, err := s.db.Query(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// HasTable :
+func (s *sqlite) HasTable(ctx context.Context, table string) bool {
+	var count int
+	s.db.QueryRow(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?;", table).Scan(&count)
+	return count > 0
+}
+
+// HasIndex :
+func (s *sqlite) HasIndex(ctx context.Context, table, idx string) bool {
+	var count int
+	s.db.QueryRow(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?;", table, idx).Scan(&count)
+	return count > 0
+}
+
+// DropIndex :
+func (s sqlite) DropIndex(table, idx string) string {
+	return fmt.Sprintf("DROP INDEX %s;", s.Quote(idx))
+}
+
+// TruncateTable :
+func (s sqlite) TruncateTable(table string) string {
+	return fmt.Sprintf("DELETE FROM %s;", s.GetTable(table))
+}
+
+// LockMode : sqlite is locking the whole database within transaction, so there is no row locking
+func (s sqlite) LockMode(locked) string {
+	return ""
+}
+
+// WrapError : classify the sqlite error by the error message, the message is in the form of
+// "UNIQUE constraint failed: Table.Column"
+func (s sqlite) WrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.As(err, new(*DriverError)) {
+		return err
+	}
+	msg := err.Error()
+	kinds := []struct {
+		prefix string
+		kind   error
+	}{
+		{"UNIQUE constraint failed: ", ErrDuplicateKey},
+		{"FOREIGN KEY constraint failed", ErrForeignKey},
+		{"NOT NULL constraint failed: ", ErrNotNull},
+		{"no such table: ", ErrTableNotFound},
+		{"no such column: ", ErrColumnNotFound},
+		{"database is locked", ErrLockTimeout},
+	}
+	for _, k := range kinds {
+		i := strings.Index(msg, k.prefix)
+		if i < 0 {
+			continue
+		}
+		de := newDriverError(k.kind, err)
+		// only the first column of the composite constraint
+		name := strings.Split(strings.TrimSpace(msg[i+len(k.prefix):]), ",")[0]
+		switch k.kind {
+		case ErrDuplicateKey, ErrNotNull:
+			if paths := strings.SplitN(name, ".", 2); len(paths) == 2 {
+				de.Table, de.Column = paths[0], paths[1]
+			}
+		case ErrTableNotFound:
+			de.Table = name
+		case ErrColumnNotFound:
+			de.Column = name
+		}
+		return de
+	}
+	return err
+}
+
+// IsRetryable : database is locked by the other connection (SQLITE_BUSY)
+func (s sqlite) IsRetryable(err error) bool {
+	return errors.Is(s.WrapError(err), ErrLockTimeout)
+}
+
+// KeyExpr : expression of the full key string of the primary key column, same as the stored key field
+func (s sqlite) KeyExpr(col, kind string) string {
+	prefix := fmt.Sprintf("rtrim(%s,replace(%s,'/',''))", col, col)
+	last := fmt.Sprintf("substr(%s,length(%s)+1)", col, prefix)
+	return fmt.Sprintf("(%s || CASE WHEN instr(%s,',')>0 THEN '' ELSE '%s,' END || %s)", prefix, last, kind, last)
+}
+
+// ParentKeyExpr : expression of the parent key string of the primary key column, NULL if it has no parent
+func (s sqlite) ParentKeyExpr(col string) string {
+	return fmt.Sprintf("NULLIF(rtrim(rtrim(%s,replace(%s,'/','')),'/'),'')", col, col)
+}
+
+// UpdateWithLimit :
+func (s sqlite) UpdateWithLimit() bool {
+	return false
+}
+
+// ReplaceInto :
+func (s *sqlite) ReplaceInto(ctx context.Context, src, dst string) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString("REPLACE INTO ")
+	buf.WriteString(s.GetTable(dst) + " ")
+	buf.WriteString("SELECT * FROM ")
+	buf.WriteString(s.GetTable(src))
+	buf.WriteString(";")
+	return s.db.execStmt(ctx, &stmt{
+		statement: buf,
+	})
+}
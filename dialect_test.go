@@ -0,0 +1,24 @@
+package goloquent
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOpenMaskPassword(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	conf := Config{Username: "root", Password: "p@ss:word", Database: "goloquent"}
+	for _, d := range []Dialect{new(mysql), new(postgres)} {
+		buf.Reset()
+		d.Open(conf)
+		if !strings.Contains(buf.String(), "Connection String") || strings.Contains(buf.String(), conf.Password) {
+			t.Errorf(errUnexpectedResult, "Open")
+		}
+	}
+}
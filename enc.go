@@ -17,6 +17,7 @@ type Registry struct {
 	sync.Mutex
 	typeEncoders map[reflect.Type]encodeFunc
 	kindEncoders map[reflect.Kind]encodeFunc
+	codecs       map[reflect.Type]Codec
 }
 
 func init() {
@@ -28,6 +29,7 @@ func NewRegistry() *Registry {
 	return &Registry{
 		typeEncoders: make(map[reflect.Type]encodeFunc),
 		kindEncoders: make(map[reflect.Kind]encodeFunc),
+		codecs:       make(map[reflect.Type]Codec),
 	}
 }
 
@@ -65,7 +67,17 @@ func (r *Registry) SetKindEncoder(k reflect.Kind, f encodeFunc) {
 	r.kindEncoders[k] = f
 }
 
+// SetCodec : the codec is used to save, load and filter the data type, it take precedence over the encoders
+func (r *Registry) SetCodec(t reflect.Type, c Codec) {
+	r.Lock()
+	defer r.Unlock()
+	r.codecs[t] = c
+}
+
 func (r *Registry) EncodeValue(v reflect.Value) (interface{}, error) {
+	if c, isOk := r.codec(v.Type()); isOk {
+		return c.Encode(v)
+	}
 	if encoder, isOk := r.typeEncoders[v.Type()]; isOk {
 		return encoder(v)
 	}
@@ -166,6 +166,8 @@ func interfaceToValue(it interface{}) (interface{}, error) {
 		value = vi
 	case json.RawMessage:
 		value = vi
+	case encodedValue:
+		value = vi.value
 	case []byte:
 		value = base64.StdEncoding.EncodeToString(vi)
 	case *datastore.Key:
@@ -262,6 +264,9 @@ func saveStructField(sc *StructCodec, v reflect.Value) (map[string]interface{},
 func saveField(f field, v reflect.Value) (interface{}, error) {
 	var it interface{}
 	t := v.Type()
+	if c, isOk := lookupCodec(t); isOk {
+		return encodeCodec(c, v)
+	}
 
 	switch vi := v.Interface().(type) {
 	case *datastore.Key, time.Time:
@@ -17,6 +17,16 @@ func (c Column) Name() string {
 	return strings.Join(c.names, ".")
 }
 
+// renamedFrom : the previous column name, flatten column keep its prefix
+func (c Column) renamedFrom() string {
+	old := c.field.renamedFrom()
+	if old == "" || c.field.isPrimaryKey() {
+		return ""
+	}
+	names := append(make([]string, 0, len(c.names)), c.names[:len(c.names)-1]...)
+	return strings.Join(append(names, old), ".")
+}
+
 func getColumns(prefix []string, codec *StructCodec) []Column {
 	columns := make([]Column, 0)
 	for _, f := range codec.fields {
@@ -122,6 +132,19 @@ func (e *entity) field(key string) field {
 	return e.fields[key].field
 }
 
+// columnName : resolve the field name into column name, flattened field is separate by "."
+func (e *entity) columnName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch name {
+	case keyFieldName, pkColumn:
+		return pkColumn, nil
+	}
+	if _, isOk := e.fields[name]; !isOk {
+		return "", fmt.Errorf("goloquent: entity %q has no field %q", e.Name(), name)
+	}
+	return name, nil
+}
+
 func (e *entity) Name() string {
 	return e.name
 }
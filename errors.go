@@ -0,0 +1,49 @@
+package goloquent
+
+import (
+	"fmt"
+)
+
+// Driver errors : the kind of the database error, which mapped by the dialect
+var (
+	ErrDuplicateKey   = fmt.Errorf("goloquent: duplicate key")
+	ErrDeadlock       = fmt.Errorf("goloquent: deadlock")
+	ErrSerialization  = fmt.Errorf("goloquent: serialization failure")
+	ErrLockTimeout    = fmt.Errorf("goloquent: lock timeout")
+	ErrForeignKey     = fmt.Errorf("goloquent: foreign key violation")
+	ErrNotNull        = fmt.Errorf("goloquent: not null violation")
+	ErrTableNotFound  = fmt.Errorf("goloquent: table not found")
+	ErrColumnNotFound = fmt.Errorf("goloquent: column not found")
+)
+
+// ErrConcurrentModification : the record is not written by `Save` or `Upsert`,
+// as the version is modified by the others or the record is not exists
+var ErrConcurrentModification = fmt.Errorf("goloquent: concurrent modification")
+
+// DriverError : the driver error which classified by the dialect, `errors.Is` match the kind of error,
+// such as `ErrDuplicateKey`, and `errors.As` still able to retrieve the original driver error
+type DriverError struct {
+	Kind       error
+	Table      string
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *DriverError) Error() string {
+	return e.Err.Error()
+}
+
+// Is :
+func (e *DriverError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap :
+func (e *DriverError) Unwrap() error {
+	return e.Err
+}
+
+func newDriverError(kind, err error) *DriverError {
+	return &DriverError{Kind: kind, Err: err}
+}
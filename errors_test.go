@@ -0,0 +1,52 @@
+package goloquent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestWrapError(t *testing.T) {
+	var de *DriverError
+	err := fmt.Errorf("goloquent: %w", new(mysql).WrapError(&mysqldriver.MySQLError{
+		Number:  1062,
+		Message: "Duplicate entry 'abc' for key 'User.PRIMARY'",
+	}))
+	if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &de) || de.Table != "User" || de.Constraint != "PRIMARY" {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+	var me *mysqldriver.MySQLError
+	if !errors.As(err, &me) || me.Number != 1062 {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+
+	err = new(mysql).WrapError(&mysqldriver.MySQLError{
+		Number:  1452,
+		Message: "Cannot add or update a child row: a foreign key constraint fails (`db`.`Order`, CONSTRAINT `fk_merchant` FOREIGN KEY (`Merchant`) REFERENCES `Merchant` (`$Key`))",
+	})
+	if !errors.Is(err, ErrForeignKey) || !errors.As(err, &de) || de.Table != "Order" || de.Column != "Merchant" || de.Constraint != "fk_merchant" {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+
+	err = new(postgres).WrapError(&pq.Error{Code: "23505", Table: "User", Column: "Email", Constraint: "User_Email_idx"})
+	if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &de) || de.Table != "User" || de.Column != "Email" {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+	err = new(postgres).WrapError(&pq.Error{Code: "42P01", Message: `relation "Log" does not exist`})
+	if !errors.Is(err, ErrTableNotFound) || !errors.As(err, &de) || de.Table != "Log" {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+
+	err = new(sqlite).WrapError(errors.New("UNIQUE constraint failed: User.$Key"))
+	if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &de) || de.Table != "User" || de.Column != "$Key" {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+
+	unknown := errors.New("unknown")
+	if new(mysql).WrapError(unknown) != unknown || new(postgres).WrapError(unknown) != unknown || new(sqlite).WrapError(unknown) != unknown {
+		t.Errorf(errUnexpectedResult, "WrapError")
+	}
+}
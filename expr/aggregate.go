@@ -0,0 +1,39 @@
+package expr
+
+// Aggregate : the Func only accept COUNT, SUM, AVG, MIN and MAX
+type Aggregate struct {
+	Func  string
+	Name  string
+	Alias string
+}
+
+// As : alias of the aggregate result
+func (a Aggregate) As(alias string) Aggregate {
+	a.Alias = alias
+	return a
+}
+
+// Count :
+func Count(name string) Aggregate {
+	return Aggregate{Func: "COUNT", Name: name}
+}
+
+// Sum :
+func Sum(name string) Aggregate {
+	return Aggregate{Func: "SUM", Name: name}
+}
+
+// Avg :
+func Avg(name string) Aggregate {
+	return Aggregate{Func: "AVG", Name: name}
+}
+
+// Min :
+func Min(name string) Aggregate {
+	return Aggregate{Func: "MIN", Name: name}
+}
+
+// Max :
+func Max(name string) Aggregate {
+	return Aggregate{Func: "MAX", Name: name}
+}
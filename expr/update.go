@@ -0,0 +1,53 @@
+package expr
+
+// UpdateOp :
+type UpdateOp int
+
+// the atomic update operators
+const (
+	OpIncrement UpdateOp = iota
+	OpDecrement
+	OpJSONSet
+	OpJSONRemove
+	OpArrayAppend
+	OpArrayRemove
+)
+
+// Update : atomic update of the column which is evaluated by the database,
+// it can be passed to `Update` directly, in slice, or as the value of the map with the same column name
+type Update struct {
+	Op     UpdateOp
+	Name   string
+	Path   string
+	Values []interface{}
+}
+
+// Increment : increase the numeric column by n
+func Increment(name string, n interface{}) Update {
+	return Update{Op: OpIncrement, Name: name, Values: []interface{}{n}}
+}
+
+// Decrement : decrease the numeric column by n
+func Decrement(name string, n interface{}) Update {
+	return Update{Op: OpDecrement, Name: name, Values: []interface{}{n}}
+}
+
+// JSONSet : set the value of the path in the json column, such as "$.address.city", the object is created if the column is NULL
+func JSONSet(name, path string, v interface{}) Update {
+	return Update{Op: OpJSONSet, Name: name, Path: path, Values: []interface{}{v}}
+}
+
+// JSONRemove : remove the path from the json column
+func JSONRemove(name, path string) Update {
+	return Update{Op: OpJSONRemove, Name: name, Path: path}
+}
+
+// ArrayAppend : append the values to the end of the slice column
+func ArrayAppend(name string, vals ...interface{}) Update {
+	return Update{Op: OpArrayAppend, Name: name, Values: vals}
+}
+
+// ArrayRemove : remove all the elements which equal to any of the values from the slice column
+func ArrayRemove(name string, vals ...interface{}) Update {
+	return Update{Op: OpArrayRemove, Name: name, Values: vals}
+}
@@ -6,15 +6,31 @@ import (
 	"time"
 
 	"cloud.google.com/go/datastore"
+	"github.com/Oskang09/goloquent/expr"
 )
 
 // Filter :
 type Filter struct {
-	field    string
-	operator operator
-	value    interface{}
-	isJSON   bool
-	raw      string
+	field     string
+	operator  operator
+	value     interface{}
+	isJSON    bool
+	raw       string
+	aggregate *expr.Aggregate
+	isOr      bool     // join with the previous filter using OR
+	group     []Filter // nested filters which wrapped with parentheses
+}
+
+func (f Filter) isOrGroup() bool {
+	if len(f.group) <= 1 {
+		return false
+	}
+	for _, ff := range f.group[1:] {
+		if !ff.isOr {
+			return false
+		}
+	}
+	return true
 }
 
 // Field :
@@ -55,6 +71,9 @@ func normalizeValue(val interface{}) (interface{}, error) {
 	v := reflect.ValueOf(val)
 	var it interface{}
 	t := v.Type()
+	if c, isOk := lookupCodec(t); isOk {
+		return encodeCodec(c, v)
+	}
 	switch vi := v.Interface().(type) {
 	case *datastore.Key:
 		if vi == nil {
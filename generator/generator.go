@@ -0,0 +1,381 @@
+// Package generator reverse engineer the goloquent model structs from the existing tables.
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Oskang09/goloquent"
+)
+
+const (
+	pkColumn         = "$Key"
+	parentColumn     = "$Parent"
+	softDeleteColumn = "$Deleted"
+	defaultPackage   = "model"
+)
+
+// default character set of the string column, see `goloquent.GetSchema`
+var defaultCharSet = goloquent.CharSet{Encoding: "utf8mb4", Collation: "utf8mb4_unicode_ci"}
+
+const (
+	importTime      = "time"
+	importJSON      = "encoding/json"
+	importDatastore = "cloud.google.com/go/datastore"
+	importGoloquent = "github.com/Oskang09/goloquent"
+)
+
+// Options :
+type Options struct {
+	// Package : package name of the generated source, default is "model"
+	Package string
+	// Tables : name of the tables to be generated, default is all the tables of the database
+	Tables []string
+}
+
+// Generate : read the schema of the tables and generate the formatted source of the model structs
+func Generate(ctx context.Context, db *goloquent.DB, opts Options) ([]byte, error) {
+	names := opts.Tables
+	if len(names) <= 0 {
+		all, err := db.Tables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		names = all
+	}
+	if len(names) <= 0 {
+		return nil, fmt.Errorf("goloquent: no table to generate")
+	}
+	tables := make([]*goloquent.TableInfo, 0, len(names))
+	for _, name := range names {
+		info, err := db.Describe(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, info)
+	}
+	return Source(db.Driver(), opts.Package, tables...)
+}
+
+// Source : generate the formatted source of the model structs from the table definitions,
+// the driver decide whether the character set of the column is applicable
+func Source(driver, pkg string, tables ...*goloquent.TableInfo) ([]byte, error) {
+	if strings.TrimSpace(pkg) == "" {
+		pkg = defaultPackage
+	}
+	imports := make(map[string]bool)
+	body := new(bytes.Buffer)
+	for _, t := range tables {
+		s := newStruct(driver, t, imports)
+		body.WriteString("\n")
+		s.write(body)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("// Code generated by goloquent from the table schema, review it before use.\n\n")
+	buf.WriteString("package " + pkg + "\n")
+	if len(imports) > 0 {
+		// standard library is grouped before the third party packages
+		std, others := make([]string, 0), make([]string, 0)
+		for p := range imports {
+			if strings.Contains(p, ".") {
+				others = append(others, p)
+			} else {
+				std = append(std, p)
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(others)
+		buf.WriteString("\nimport (\n")
+		for _, p := range std {
+			buf.WriteString(fmt.Sprintf("%q\n", p))
+		}
+		if len(std) > 0 && len(others) > 0 {
+			buf.WriteString("\n")
+		}
+		for _, p := range others {
+			buf.WriteString(fmt.Sprintf("%q\n", p))
+		}
+		buf.WriteString(")\n")
+	}
+	buf.Write(body.Bytes())
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("goloquent: %w", err)
+	}
+	return b, nil
+}
+
+// node : the struct field, flatten struct has children instead of data type
+type node struct {
+	name     string
+	column   string
+	dataType string
+	options  []string
+	comment  string
+	children []*node
+}
+
+func (n *node) child(column string) *node {
+	for _, c := range n.children {
+		if c.column == column && c.dataType == "" {
+			return c
+		}
+	}
+	return n.add(&node{name: exportName(column), column: column, options: []string{"flatten"}})
+}
+
+// add : append the field, the name is suffixed with number when it collides with the other field,
+// such as "a_b" and "aB", the column is kept by the tag
+func (n *node) add(c *node) *node {
+	if c.name != "" {
+		name := c.name
+		for i := 2; n.has(name); i++ {
+			name = fmt.Sprintf("%s%d", c.name, i)
+		}
+		c.name = name
+	}
+	n.children = append(n.children, c)
+	return c
+}
+
+func (n *node) has(name string) bool {
+	for _, c := range n.children {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) tag() string {
+	name := ""
+	if n.name != n.column {
+		name = n.column
+	}
+	if name == "" && len(n.options) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("`goloquent:\"%s\"`", strings.Join(append([]string{name}, n.options...), ","))
+}
+
+func (n *node) write(buf *bytes.Buffer) {
+	if n.comment != "" {
+		buf.WriteString("// " + n.comment + "\n")
+	}
+	if n.name == "" {
+		return
+	}
+	buf.WriteString(n.name + " ")
+	if n.dataType != "" {
+		buf.WriteString(n.dataType + " " + n.tag() + "\n")
+		return
+	}
+	buf.WriteString("struct {\n")
+	for _, c := range n.children {
+		c.write(buf)
+	}
+	buf.WriteString("} " + n.tag() + "\n")
+}
+
+type model struct {
+	name   string
+	table  string
+	fields *node
+}
+
+func (m *model) write(buf *bytes.Buffer) {
+	buf.WriteString(fmt.Sprintf("// %s : generated from table %q\n", m.name, m.table))
+	buf.WriteString(fmt.Sprintf("type %s struct {\n", m.name))
+	for _, c := range m.fields.children {
+		c.write(buf)
+	}
+	buf.WriteString("}\n")
+}
+
+func newStruct(driver string, t *goloquent.TableInfo, imports map[string]bool) *model {
+	root := new(node)
+	for _, c := range t.Columns {
+		switch c.Name {
+		case pkColumn:
+			imports[importDatastore] = true
+			root.add(&node{
+				name:     "Key",
+				column:   "__key__",
+				dataType: "*datastore.Key",
+			})
+			continue
+		case parentColumn:
+			root.add(&node{
+				comment: fmt.Sprintf("%s is the parent of the key", parentColumn),
+			})
+			continue
+		case softDeleteColumn:
+			imports[importGoloquent] = true
+			root.add(&node{
+				name:     "Deleted",
+				column:   "Deleted",
+				dataType: "goloquent.SoftDelete",
+			})
+			continue
+		}
+
+		if !isValidColumn(c.Name) {
+			root.add(&node{
+				comment: fmt.Sprintf("column %q is skipped, it is not a valid field name", c.Name),
+			})
+			continue
+		}
+
+		paths := strings.Split(c.Name, ".")
+		parent := root
+		for _, p := range paths[:len(paths)-1] {
+			parent = parent.child(p)
+		}
+		column := paths[len(paths)-1]
+		dataType, options := goType(driver, c, imports)
+		parent.add(&node{
+			name:     exportName(column),
+			column:   column,
+			dataType: dataType,
+			options:  options,
+		})
+	}
+	return &model{name: exportName(t.Name), table: t.Name, fields: root}
+}
+
+// goType : data type of the field and the tag options, it is the reverse of `GetSchema`
+func goType(driver string, c goloquent.ColumnInfo, imports map[string]bool) (string, []string) {
+	dt := strings.ToLower(strings.TrimSpace(c.DataType))
+	isUnsigned := strings.Contains(dt, "unsigned")
+	base, args := dt, ""
+	if i := strings.Index(dt, "("); i > -1 {
+		base = strings.TrimSpace(dt[:i])
+		if j := strings.Index(dt, ")"); j > i {
+			args = dt[i+1 : j]
+		}
+	}
+	base = strings.TrimSpace(strings.TrimSuffix(base, "unsigned"))
+
+	options := make([]string, 0)
+	integer := func(signed, unsigned string) string {
+		if isUnsigned {
+			return unsigned
+		}
+		return signed
+	}
+	var t string
+	switch base {
+	case "bool", "boolean":
+		t = "bool"
+	case "tinyint":
+		t = integer("int8", "uint8")
+		if args == "1" {
+			t = "bool"
+		}
+	case "smallint", "int2":
+		t = integer("int16", "uint16")
+	case "mediumint":
+		t = integer("int32", "uint32")
+	case "int", "integer", "int4":
+		t = integer("int", "uint")
+	case "bigint", "int8":
+		t = integer("int64", "uint64")
+	case "double", "float", "real", "double precision", "float4", "float8":
+		t = "float64"
+		if isUnsigned {
+			options = append(options, "unsigned")
+		}
+	case "datetime", "timestamp", "timestamptz":
+		imports[importTime] = true
+		t = "time.Time"
+	case "date":
+		imports[importGoloquent] = true
+		t = "goloquent.Date"
+	case "json", "jsonb":
+		imports[importJSON] = true
+		return "json.RawMessage", options
+	case "blob", "tinyblob", "mediumblob", "longblob", "bytea":
+		return "[]byte", options
+	case "varchar", "character varying":
+		// key column is stored as varchar(512), with latin1 character set in mysql
+		if args == "512" && (driver != "mysql" || c.Encoding == "latin1") {
+			imports[importDatastore] = true
+			return "*datastore.Key", options
+		}
+		t = "string"
+		if args != "191" {
+			options = append(options, "datatype="+dt)
+		}
+		options = append(options, charset(driver, c)...)
+	case "text":
+		t = "string"
+		options = append(options, "longtext")
+		options = append(options, charset(driver, c)...)
+	default:
+		t = "string"
+		options = append(options, "datatype="+dt)
+		options = append(options, charset(driver, c)...)
+	}
+	if c.IsNullable {
+		t = "*" + t
+	}
+	return t, options
+}
+
+// charset : the character set is only applicable in mysql, the collation is omitted if it's the default of the character set
+func charset(driver string, c goloquent.ColumnInfo) []string {
+	if driver != "mysql" || c.Encoding == "" || c.CharSet == defaultCharSet {
+		return nil
+	}
+	options := []string{"charset=" + strings.ToLower(c.Encoding)}
+	if c.Collation != "" && !strings.EqualFold(c.Collation, c.Encoding+"_general_ci") {
+		options = append(options, "collate="+strings.ToLower(c.Collation))
+	}
+	return options
+}
+
+// exportName : convert the name into exported identifier, such as "created_at" to "CreatedAt"
+func exportName(name string) string {
+	buf := new(strings.Builder)
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if buf.Len() == 0 && unicode.IsDigit(r) {
+			buf.WriteRune('X')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		buf.WriteRune(r)
+	}
+	if buf.Len() == 0 {
+		return "X"
+	}
+	return buf.String()
+}
+
+// isValidColumn : the column name must be mapped by the field name, see `isValidFieldName`
+func isValidColumn(name string) bool {
+	for _, s := range strings.Split(name, ".") {
+		if s == "" {
+			return false
+		}
+		for i, c := range s {
+			if c == '_' || unicode.IsLetter(c) || (i > 0 && unicode.IsDigit(c)) {
+				continue
+			}
+			return false
+		}
+	}
+	return true
+}
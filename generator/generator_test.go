@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Oskang09/goloquent"
+)
+
+func TestSource(t *testing.T) {
+	def := "''"
+	info := &goloquent.TableInfo{
+		Name: "user_profile",
+		Columns: []goloquent.ColumnInfo{
+			{Name: "$Key", DataType: "varchar(512)", CharSet: goloquent.CharSet{Encoding: "latin1", Collation: "latin1_bin"}},
+			{Name: "$Parent", DataType: "varchar(512)", CharSet: goloquent.CharSet{Encoding: "latin1", Collation: "latin1_bin"}},
+			{Name: "Name", DataType: "varchar(191)", Default: &def, CharSet: goloquent.CharSet{Encoding: "utf8mb4", Collation: "utf8mb4_unicode_ci"}},
+			{Name: "phone_no", DataType: "char(20)", CharSet: goloquent.CharSet{Encoding: "utf8", Collation: "utf8_bin"}},
+			{Name: "Code", DataType: "varchar(50)", CharSet: goloquent.CharSet{Encoding: "latin1", Collation: "latin1_general_ci"}},
+			{Name: "Merchant", DataType: "varchar(512)", IsNullable: true, CharSet: goloquent.CharSet{Encoding: "latin1", Collation: "latin1_bin"}},
+			{Name: "IsActive", DataType: "tinyint(1)"},
+			{Name: "Age", DataType: "tinyint(3) unsigned"},
+			{Name: "Credit", DataType: "double unsigned"},
+			{Name: "Score", DataType: "int(11)", IsNullable: true},
+			{Name: "Address.Region.Code", DataType: "varchar(191)"},
+			{Name: "first-name", DataType: "varchar(191)"},
+			{Name: "$Deleted", DataType: "datetime", IsNullable: true},
+		},
+	}
+	b, err := Source("mysql", "", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := strings.Join(strings.Fields(string(b)), " ")
+	for _, line := range []string{
+		"package model",
+		"type UserProfile struct {",
+		"Key *datastore.Key `goloquent:\"__key__\"` // $Parent is the parent of the key",
+		"Name string PhoneNo string `goloquent:\"phone_no,datatype=char(20),charset=utf8,collate=utf8_bin\"`",
+		"Code string `goloquent:\",datatype=varchar(50),charset=latin1\"`",
+		"Merchant *datastore.Key",
+		"IsActive bool",
+		"Age uint8",
+		"Credit float64 `goloquent:\",unsigned\"`",
+		"Score *int",
+		"Address struct { Region struct { Code string } `goloquent:\",flatten\"` } `goloquent:\",flatten\"`",
+		"// column \"first-name\" is skipped",
+		"Deleted goloquent.SoftDelete",
+	} {
+		if !strings.Contains(src, line) {
+			t.Fatalf("generated source should contain %q, source:\n%s", line, b)
+		}
+	}
+}
+
+func TestExportName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"created_at": "CreatedAt",
+		"Name":       "Name",
+		"2fa":        "X2fa",
+		"user-id":    "UserId",
+		"$":          "X",
+	} {
+		if n := exportName(name); n != expected {
+			t.Fatalf("unexpected export name of %q, %q", name, n)
+		}
+	}
+}
+
+func TestSourceCollision(t *testing.T) {
+	info := &goloquent.TableInfo{
+		Name: "collision",
+		Columns: []goloquent.ColumnInfo{
+			{Name: "$Key", DataType: "varchar(512)"},
+			{Name: "a_b", DataType: "int"},
+			{Name: "aB", DataType: "int"},
+			{Name: "AB", DataType: "int"},
+			{Name: "key", DataType: "int"},
+			{Name: "x.a_b", DataType: "int"},
+			{Name: "x.aB", DataType: "int"},
+		},
+	}
+	b, err := Source("sqlite3", "", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := strings.Join(strings.Fields(string(b)), " ")
+	for _, line := range []string{
+		"Key *datastore.Key `goloquent:\"__key__\"`",
+		"AB int `goloquent:\"a_b\"` AB2 int `goloquent:\"aB\"` AB3 int `goloquent:\"AB\"`",
+		"Key2 int `goloquent:\"key\"`",
+		"X struct { AB int `goloquent:\"a_b\"` AB2 int `goloquent:\"aB\"` }",
+	} {
+		if !strings.Contains(src, line) {
+			t.Fatalf("generated source should contain %q, source:\n%s", line, b)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package goloquent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// BeforeCreate : invoked by `Create` and `Upsert` before the entity is inserted, after `Saver`
+type BeforeCreate interface {
+	BeforeCreate(ctx context.Context, db *DB) error
+}
+
+// AfterCreate : invoked by `Create` and `Upsert` after the entity is inserted
+type AfterCreate interface {
+	AfterCreate(ctx context.Context, db *DB) error
+}
+
+// BeforeUpdate : invoked by `Save`, and `Upsert` when the record exists, before the entity is updated, after `Saver`,
+// the record is looked up before the write of `Upsert`, so it's best effort when the record is inserted concurrently
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context, db *DB) error
+}
+
+// AfterUpdate : invoked by `Save`, and `Upsert` when the record exists, after the entity is updated
+type AfterUpdate interface {
+	AfterUpdate(ctx context.Context, db *DB) error
+}
+
+// BeforeDelete : invoked by `Delete` and `Destroy` before the entity is deleted
+type BeforeDelete interface {
+	BeforeDelete(ctx context.Context, db *DB) error
+}
+
+// AfterDelete : invoked by `Delete` and `Destroy` after the entity is deleted
+type AfterDelete interface {
+	AfterDelete(ctx context.Context, db *DB) error
+}
+
+// AfterFind : invoked after the entity is loaded from the record, after `Loader`,
+// the relations is not yet loaded and the rows of `Iter` is still open
+type AfterFind interface {
+	AfterFind(ctx context.Context, db *DB) error
+}
+
+// hasWriteHooks : report whether the entity implements any of the create or update hooks
+func hasWriteHooks(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	for _, it := range []reflect.Type{
+		reflect.TypeOf((*BeforeCreate)(nil)).Elem(),
+		reflect.TypeOf((*AfterCreate)(nil)).Elem(),
+		reflect.TypeOf((*BeforeUpdate)(nil)).Elem(),
+		reflect.TypeOf((*AfterUpdate)(nil)).Elem(),
+	} {
+		if pt.Implements(it) {
+			return true
+		}
+	}
+	return false
+}
+
+// hook : invoke the hook of the entities in the slice, the hook is invoked with the pointer
+// of the entity, so the changes is kept
+func hook(v reflect.Value, cb func(interface{}) error) error {
+	for i := 0; i < v.Len(); i++ {
+		f := v.Index(i)
+		if f.Kind() != reflect.Ptr {
+			f = f.Addr()
+		}
+		if f.IsNil() {
+			continue
+		}
+		if err := cb(f.Interface()); err != nil {
+			return fmt.Errorf("goloquent: %w", err)
+		}
+	}
+	return nil
+}
+
+func afterCreate(ctx context.Context, db *DB, v reflect.Value) error {
+	return hook(v, func(it interface{}) error {
+		if x, isOk := it.(AfterCreate); isOk {
+			return x.AfterCreate(ctx, db)
+		}
+		return nil
+	})
+}
+
+func beforeDelete(ctx context.Context, db *DB, v reflect.Value) error {
+	return hook(v, func(it interface{}) error {
+		if x, isOk := it.(BeforeDelete); isOk {
+			return x.BeforeDelete(ctx, db)
+		}
+		return nil
+	})
+}
+
+func afterDelete(ctx context.Context, db *DB, v reflect.Value) error {
+	return hook(v, func(it interface{}) error {
+		if x, isOk := it.(AfterDelete); isOk {
+			return x.AfterDelete(ctx, db)
+		}
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package goloquent
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -24,11 +25,14 @@ type Saver interface {
 
 // Iterator :
 type Iterator struct {
+	db       *DB // it is passed to `AfterFind`
 	table    string
 	stmt     *Stmt
-	sign     string
-	position int // current record position
+	sign     string // digest of the query which produce the result set
+	secret   []byte // secret to sign the cursor
+	position int    // current record position
 	columns  []string
+	orders   []string // sort columns, the cursor will carry their values
 	results  []map[string][]byte
 }
 
@@ -80,6 +84,23 @@ func (it *Iterator) put(pos int, k string, v interface{}) error {
 	return nil
 }
 
+// scanRow read the current row of `rows` and append it into the result set
+func (it *Iterator) scanRow(rows *sql.Rows) error {
+	m := make([]interface{}, len(it.columns))
+	for j := range it.columns {
+		m[j] = &m[j]
+	}
+	if err := rows.Scan(m...); err != nil {
+		return err
+	}
+	pos := len(it.results)
+	for j, name := range it.columns {
+		it.put(pos, name, m[j])
+	}
+	it.patchKey()
+	return nil
+}
+
 // First :
 func (it *Iterator) First() *Iterator {
 	it.position = 0
@@ -110,30 +131,44 @@ func (it Iterator) Count() uint {
 	return uint(len(it.results))
 }
 
-func (it *Iterator) signature() string {
-	if it.sign == "" {
-		it.sign = sha1Sign(it.stmt)
-	}
-	return it.sign
-}
-
-// Cursor :
-func (it *Iterator) Cursor() (Cursor, error) {
-	if it.position+1 > len(it.results)-1 {
+// cursorAt : cursor which point to the record set strictly after the record at `pos`
+func (it *Iterator) cursorAt(pos int, backward bool) (Cursor, error) {
+	if pos < 0 || pos > len(it.results)-1 {
 		return Cursor{}, fmt.Errorf("goloquent: interator out of index result range")
 	}
-	key, err := parseKey(string(it.results[it.position+1][keyFieldName]))
+	l := it.results[pos]
+	key, err := parseKey(string(l[keyFieldName]))
 	if err != nil {
 		return Cursor{}, fmt.Errorf("goloquent: missing cursor key")
 	}
+	values := make([]interface{}, 0, len(it.orders))
+	for _, name := range it.orders {
+		b, isOk := l[name]
+		if !isOk {
+			return Cursor{}, fmt.Errorf("goloquent: missing order field %q for cursor", name)
+		}
+		if b == nil {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, string(b))
+	}
 	c := Cursor{
-		Signature: it.signature(),
+		Signature: it.sign,
 		Key:       key,
+		Values:    values,
+		Backward:  backward,
 	}
 	c.cc, _ = json.Marshal(c)
+	c.sign(it.secret)
 	return c, nil
 }
 
+// Cursor : cursor of the record set after current record
+func (it *Iterator) Cursor() (Cursor, error) {
+	return it.cursorAt(it.position, false)
+}
+
 // Next : go next record
 func (it *Iterator) Next() bool {
 	it.position++
@@ -157,18 +192,10 @@ func (it *Iterator) scan(ctx context.Context, src interface{}) (map[string]inter
 	data := make(map[string]interface{})
 	for _, f := range codec.fields {
 		fv := getField(nv.Elem(), f.paths)
-		props := getTypes(nil, f, f.isFlatten())
-		for i, p := range props {
-			k := p.Name()
-			b := it.Get(k)
-			var vv, err = valueToInterface(p.typeOf, b, false)
-			if err != nil {
-				return nil, err
-			}
-			props[i].Value = vv
+		vi, err := it.decodeField(f)
+		if err != nil {
+			return nil, err
 		}
-
-		vi := denormalize(f, props)
 		data[f.name] = vi
 		if err := loadField(fv, vi); err != nil {
 			return nil, err
@@ -177,7 +204,12 @@ func (it *Iterator) scan(ctx context.Context, src interface{}) (map[string]inter
 
 	if l, isOk := nv.Interface().(Loader); isOk {
 		if err := l.Load(ctx); err != nil {
-			return nil, fmt.Errorf("goloquent: %v", err)
+			return nil, fmt.Errorf("goloquent: %w", err)
+		}
+	}
+	if l, isOk := nv.Interface().(AfterFind); isOk {
+		if err := l.AfterFind(ctx, it.db); err != nil {
+			return nil, fmt.Errorf("goloquent: %w", err)
 		}
 	}
 
@@ -185,6 +217,19 @@ func (it *Iterator) scan(ctx context.Context, src interface{}) (map[string]inter
 	return data, nil
 }
 
+// decodeField : value of the field from the current record, the hooks is not invoked
+func (it *Iterator) decodeField(f field) (interface{}, error) {
+	props := getTypes(nil, f, f.isFlatten())
+	for i, p := range props {
+		vv, err := valueToInterface(p.typeOf, it.Get(p.Name()), false)
+		if err != nil {
+			return nil, err
+		}
+		props[i].Value = vv
+	}
+	return denormalize(f, props), nil
+}
+
 // Scan : set the model value
 func (it *Iterator) Scan(ctx context.Context, src interface{}) error {
 	if _, err := it.scan(ctx, src); err != nil {
@@ -193,6 +238,72 @@ func (it *Iterator) Scan(ctx context.Context, src interface{}) error {
 	return nil
 }
 
+// StreamIterator : iterate the result set row by row instead of loading the whole result set into memory
+type StreamIterator struct {
+	rows *sql.Rows
+	it   Iterator // hold the current record
+	err  error
+}
+
+func (si *StreamIterator) fetch() bool {
+	if !si.rows.Next() {
+		si.err = si.rows.Err()
+		return false
+	}
+	if err := si.it.scanRow(si.rows); err != nil {
+		si.err = fmt.Errorf("goloquent: %w", err)
+		return false
+	}
+	return true
+}
+
+// Next : go next record, it will close the iterator when there is no more record
+func (si *StreamIterator) Next() bool {
+	if si.err != nil {
+		return false
+	}
+	si.it.results = si.it.results[:0]
+	if !si.fetch() {
+		si.Close()
+		return false
+	}
+	return true
+}
+
+// Scan : set the model value using current record
+func (si *StreamIterator) Scan(ctx context.Context, src interface{}) error {
+	if len(si.it.results) <= 0 {
+		return fmt.Errorf("goloquent: iterator has no current record, call Next before Scan")
+	}
+	if _, err := si.it.scan(ctx, src); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get : get value of current record by key
+func (si *StreamIterator) Get(k string) []byte {
+	if len(si.it.results) <= 0 {
+		return nil
+	}
+	return si.it.Get(k)
+}
+
+// Cursor : cursor of the record set after current record
+func (si *StreamIterator) Cursor() (Cursor, error) {
+	return si.it.Cursor()
+}
+
+// Err : return the error encountered during iteration
+func (si *StreamIterator) Err() error {
+	return si.err
+}
+
+// Close : release the underlying connection, it's safe to call multiple times
+func (si *StreamIterator) Close() error {
+	return si.rows.Close()
+}
+
 func getField(v reflect.Value, path []int) reflect.Value {
 	for i, p := range path {
 		v = v.Field(p)
@@ -280,6 +391,8 @@ func interfaceIsZero(it interface{}) bool {
 		zero = vi == 0
 	case SoftDelete:
 		zero = vi == SoftDelete(nil)
+	case encodedValue:
+		zero = vi.value == nil || interfaceIsZero(vi.value)
 	case *datastore.Key:
 		zero = vi == nil || (*vi) == datastore.Key{}
 	case datastore.GeoPoint:
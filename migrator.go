@@ -0,0 +1,432 @@
+package goloquent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	defaultMigrationTable = "goloquent_migrations"
+	migrationLockName     = "lock"
+)
+
+// ErrMigrationLocked : the other instance is migrating
+var ErrMigrationLocked = fmt.Errorf("goloquent: migration is locked by the other instance")
+
+// MigrationFunc :
+type MigrationFunc func(ctx context.Context, tx *DB) error
+
+// Migration : versioned migration, it is either the Go function or the SQL statements,
+// the version is sorted lexically, so it should be zero padded number or timestamp, such as "20210801120000"
+type Migration struct {
+	Version string
+	Name    string
+	Up      MigrationFunc
+	Down    MigrationFunc
+	UpSQL   string
+	DownSQL string
+}
+
+func (m Migration) up() MigrationFunc {
+	if m.Up != nil {
+		return m.Up
+	}
+	return execSQL(m.UpSQL)
+}
+
+func (m Migration) down() MigrationFunc {
+	if m.Down != nil {
+		return m.Down
+	}
+	if strings.TrimSpace(m.DownSQL) == "" {
+		return nil
+	}
+	return execSQL(m.DownSQL)
+}
+
+func execSQL(s string) MigrationFunc {
+	return func(ctx context.Context, tx *DB) error {
+		for _, stmt := range splitStatements(s) {
+			if err := tx.client.execCommand(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// MigrationStatus :
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Missing : the applied migration is not registered in the migrator
+	Missing bool
+}
+
+type migrationHistory struct {
+	Key       *datastore.Key `goloquent:"__key__"`
+	Version   string
+	Name      string
+	AppliedAt time.Time
+}
+
+type migrationLock struct {
+	Key      *datastore.Key `goloquent:"__key__"`
+	LockedAt time.Time
+}
+
+// Migrator : run the versioned migrations, the applied versions is recorded in the history table
+type Migrator struct {
+	db           *DB
+	table        string
+	pollInterval time.Duration
+	migrations   map[string]Migration
+}
+
+// NewMigrator :
+func NewMigrator(db *DB) *Migrator {
+	return &Migrator{
+		db:           db,
+		table:        defaultMigrationTable,
+		pollInterval: time.Second,
+		migrations:   make(map[string]Migration),
+	}
+}
+
+// SetTable : name of the history table, the lock table will be suffixed with "_lock"
+func (m *Migrator) SetTable(name string) *Migrator {
+	if name = strings.TrimSpace(name); name != "" {
+		m.table = name
+	}
+	return m
+}
+
+// Add : register the migrations, the version must be unique
+func (m *Migrator) Add(migrations ...Migration) error {
+	for _, mg := range migrations {
+		mg.Version = strings.TrimSpace(mg.Version)
+		if mg.Version == "" {
+			return fmt.Errorf("goloquent: migration version is required")
+		}
+		if mg.Up == nil && strings.TrimSpace(mg.UpSQL) == "" {
+			return fmt.Errorf("goloquent: migration %q has no up migration", mg.Version)
+		}
+		if _, isExist := m.migrations[mg.Version]; isExist {
+			return fmt.Errorf("goloquent: duplicate migration version %q", mg.Version)
+		}
+		m.migrations[mg.Version] = mg
+	}
+	return nil
+}
+
+var migrationFileRgx = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// AddFS : register the SQL file migrations in the directory,
+// the file name is in the form of "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+func (m *Migrator) AddFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("goloquent: %w", err)
+	}
+	files := make(map[string]Migration)
+	for _, entry := range entries {
+		result := migrationFileRgx.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || result == nil {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("goloquent: %w", err)
+		}
+		mg := files[result[1]]
+		mg.Version, mg.Name = result[1], result[2]
+		if result[3] == "up" {
+			mg.UpSQL = string(b)
+		} else {
+			mg.DownSQL = string(b)
+		}
+		files[result[1]] = mg
+	}
+	for _, mg := range files {
+		if err := m.Add(mg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) lockTable() string {
+	return m.table + "_lock"
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	if err := m.db.Table(m.table).Migrate(ctx, new(migrationHistory)); err != nil {
+		return err
+	}
+	return m.db.Table(m.lockTable()).Migrate(ctx, new(migrationLock))
+}
+
+// lock : acquire the lock by inserting the lock record, it wait until the lock is released or the context is done
+func (m *Migrator) lock(ctx context.Context) error {
+	for {
+		err := m.db.Table(m.lockTable()).Create(ctx, &migrationLock{
+			Key:      datastore.NameKey(m.lockTable(), migrationLockName, nil),
+			LockedAt: time.Now().UTC(),
+		})
+		if !errors.Is(err, ErrDuplicateKey) {
+			return err
+		}
+		if err := sleep(ctx, m.pollInterval); err != nil {
+			return ErrMigrationLocked
+		}
+	}
+}
+
+// Unlock : force release the lock, such as the migrating instance is crashed
+func (m *Migrator) Unlock(ctx context.Context) error {
+	return m.db.Table(m.lockTable()).
+		Where(keyFieldName, "=", datastore.NameKey(m.lockTable(), migrationLockName, nil)).
+		Flush(ctx)
+}
+
+func (m *Migrator) withLock(ctx context.Context, cb func() error) error {
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.Unlock(context.Background())
+	return cb()
+}
+
+func (m *Migrator) histories(ctx context.Context) ([]migrationHistory, error) {
+	histories := make([]migrationHistory, 0)
+	if err := m.db.Table(m.table).OrderBy("Version").Get(ctx, &histories); err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func (m *Migrator) sorted() []Migration {
+	migrations := make([]Migration, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		migrations = append(migrations, mg)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+func (m *Migrator) pending(ctx context.Context) ([]Migration, error) {
+	histories, err := m.histories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]bool)
+	for _, h := range histories {
+		applied[h.Version] = true
+	}
+	migrations := make([]Migration, 0)
+	for _, mg := range m.sorted() {
+		if !applied[mg.Version] {
+			migrations = append(migrations, mg)
+		}
+	}
+	return migrations, nil
+}
+
+// Pending : the registered migrations which not yet applied
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	return m.pending(ctx)
+}
+
+// Status : status of the registered and applied migrations, sorted by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	histories, err := m.histories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dict := make(map[string]MigrationStatus)
+	for _, mg := range m.migrations {
+		dict[mg.Version] = MigrationStatus{Version: mg.Version, Name: mg.Name}
+	}
+	for _, h := range histories {
+		s, isExist := dict[h.Version]
+		if !isExist {
+			s = MigrationStatus{Version: h.Version, Name: h.Name, Missing: true}
+		}
+		s.Applied, s.AppliedAt = true, h.AppliedAt
+		dict[h.Version] = s
+	}
+	status := make([]MigrationStatus, 0, len(dict))
+	for _, s := range dict {
+		status = append(status, s)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+	return status, nil
+}
+
+// Up : apply all the pending migrations in order, each migration is run in its own transaction
+// along with the history record, noted that DDL statement is implicitly committed in mysql
+// and `Migrate` of the transaction is not executed within the transaction
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func() error {
+		migrations, err := m.pending(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			mg := mg
+			if err := m.db.RunInTransactionCtx(ctx, func(tx *DB) error {
+				if err := mg.up()(ctx, tx); err != nil {
+					return fmt.Errorf("goloquent: migration %q up failed, %w", mg.Version, err)
+				}
+				return tx.Table(m.table).Create(ctx, &migrationHistory{
+					Key:       datastore.NameKey(m.table, mg.Version, nil),
+					Version:   mg.Version,
+					Name:      mg.Name,
+					AppliedAt: time.Now().UTC(),
+				})
+			}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down : revert the last `steps` applied migrations in reverse order
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		histories, err := m.histories(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(histories) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+			h := histories[i]
+			mg, isExist := m.migrations[h.Version]
+			if !isExist || mg.down() == nil {
+				return fmt.Errorf("goloquent: migration %q has no down migration", h.Version)
+			}
+			if err := m.db.RunInTransactionCtx(ctx, func(tx *DB) error {
+				if err := mg.down()(ctx, tx); err != nil {
+					return fmt.Errorf("goloquent: migration %q down failed, %w", mg.Version, err)
+				}
+				return tx.Table(m.table).
+					Where(keyFieldName, "=", datastore.NameKey(m.table, h.Version, nil)).
+					Flush(ctx)
+			}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// splitStatements : split the SQL script by semicolon, semicolon within quote, dollar quote
+// (such as `$$` and `$tag$` of postgres) and comment is ignored, the backslash escape the
+// quote within the string same as mysql
+func splitStatements(s string) []string {
+	stmts := make([]string, 0)
+	buf := new(strings.Builder)
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		buf.Reset()
+	}
+	var quote rune
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' && i+1 < len(runes) {
+				buf.WriteRune(c)
+				i++
+				c = runes[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			buf.WriteRune('\n')
+			continue
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 3
+			for i < len(runes) && !(runes[i-1] == '*' && runes[i] == '/') {
+				i++
+			}
+			buf.WriteRune(' ')
+			continue
+		case c == '$' && (i == 0 || !isIdentRune(runes[i-1])):
+			tag := dollarQuoteTag(runes[i:])
+			if tag == nil {
+				break
+			}
+			// copy the dollar quoted string as it is until the closing tag
+			end := len(runes)
+			for j := i + len(tag); j+len(tag) <= len(runes); j++ {
+				if string(runes[j:j+len(tag)]) == string(tag) {
+					end = j + len(tag)
+					break
+				}
+			}
+			buf.WriteString(string(runes[i:end]))
+			i = end - 1
+			continue
+		case c == ';':
+			flush()
+			continue
+		}
+		buf.WriteRune(c)
+	}
+	flush()
+	return stmts
+}
+
+// dollarQuoteTag : the opening tag of the postgres dollar quote, such as `$$` or `$body$`,
+// nil if it's not a dollar quote, such as the positional parameter `$1`
+func dollarQuoteTag(runes []rune) []rune {
+	for i := 1; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '$':
+			return runes[:i+1]
+		case isIdentRune(c) && !(i == 1 && unicode.IsDigit(c)):
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || c == '$' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
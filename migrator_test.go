@@ -0,0 +1,64 @@
+package goloquent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	stmts := splitStatements(`
+-- create table; with comment
+CREATE TABLE "Log" ("Message" varchar(191) DEFAULT 'a;b');
+INSERT INTO "Log" VALUES ('c');
+`)
+	if !reflect.DeepEqual(stmts, []string{
+		`CREATE TABLE "Log" ("Message" varchar(191) DEFAULT 'a;b')`,
+		`INSERT INTO "Log" VALUES ('c')`,
+	}) {
+		t.Errorf(errUnexpectedResult, "splitStatements")
+	}
+}
+
+func TestSplitStatementsBlockComment(t *testing.T) {
+	stmts := splitStatements(`
+/* create table;
+   with block comment */
+CREATE TABLE "Log" ("Message" varchar(191)); /*/ not closed; */
+INSERT INTO "Log" VALUES ('c');
+`)
+	if !reflect.DeepEqual(stmts, []string{
+		`CREATE TABLE "Log" ("Message" varchar(191))`,
+		`INSERT INTO "Log" VALUES ('c')`,
+	}) {
+		t.Errorf(errUnexpectedResult, "splitStatements")
+	}
+}
+
+func TestSplitStatementsDollarQuote(t *testing.T) {
+	fn := `CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+	NEW."UpdatedAt" = now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+	tagged := `DO $body$ BEGIN PERFORM 1; END $body$`
+	stmts := splitStatements(fn + ";\n" + tagged + ";\nSELECT $1, a$b$c FROM \"Log\";")
+	if !reflect.DeepEqual(stmts, []string{
+		fn,
+		tagged,
+		`SELECT $1, a$b$c FROM "Log"`,
+	}) {
+		t.Errorf(errUnexpectedResult, "splitStatements")
+	}
+}
+
+func TestSplitStatementsBackslashEscape(t *testing.T) {
+	stmts := splitStatements(`INSERT INTO Log VALUES ('it\'s; fine', "say \"hi;\"", 'C:\\');
+INSERT INTO Log VALUES ('it''s');`)
+	if !reflect.DeepEqual(stmts, []string{
+		`INSERT INTO Log VALUES ('it\'s; fine', "say \"hi;\"", 'C:\\')`,
+		`INSERT INTO Log VALUES ('it''s')`,
+	}) {
+		t.Errorf(errUnexpectedResult, "splitStatements")
+	}
+}
@@ -11,6 +11,7 @@ type Pagination struct {
 	Limit     uint
 	count     uint
 	nxtCursor Cursor
+	prvCursor Cursor
 }
 
 // SetQuery :
@@ -33,6 +34,11 @@ func (p *Pagination) NextCursor() string {
 	return p.nxtCursor.String()
 }
 
+// PrevCursor : previous record set cursor
+func (p *Pagination) PrevCursor() string {
+	return p.prvCursor.String()
+}
+
 // Count : record count in this pagination record set
 func (p *Pagination) Count() uint {
 	return p.count
@@ -0,0 +1,146 @@
+package goloquent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+)
+
+// SchemaAction : action of the schema change
+type SchemaAction string
+
+// Schema actions :
+const (
+	ActionCreateTable  SchemaAction = "create_table"
+	ActionAddColumn    SchemaAction = "add_column"
+	ActionModifyColumn SchemaAction = "modify_column"
+	ActionRenameColumn SchemaAction = "rename_column"
+	ActionDropColumn   SchemaAction = "drop_column"
+	ActionAddIndex     SchemaAction = "add_index"
+	ActionDropIndex    SchemaAction = "drop_index"
+)
+
+// SchemaChange : the difference between the model and the existing table
+type SchemaChange struct {
+	Action SchemaAction
+	Column string
+	Index  string
+	// RenamedFrom : the previous name of the renamed column
+	RenamedFrom string
+	// From : the existing data type of the column
+	From string
+	// To : the data type of the column derived from the model
+	To string
+	// Destructive : the change may lose the existing data
+	Destructive bool
+	// Skipped : the change is detected but `Migrate` won't apply it, so there is no statement for it,
+	// such as dropping the stale column and index which require `Unsafe`
+	Skipped bool
+}
+
+// TablePlan : the changes and statements of a table
+type TablePlan struct {
+	Table      string
+	Create     bool
+	Changes    []SchemaChange
+	Statements []string
+}
+
+func (p *TablePlan) addChange(c SchemaChange) {
+	p.Changes = append(p.Changes, c)
+}
+
+func (p *TablePlan) addStatement(buf *bytes.Buffer) {
+	p.Statements = append(p.Statements, buf.String())
+}
+
+// IsDestructive : report whether any of the applicable change may lose the existing data
+func (p TablePlan) IsDestructive() bool {
+	for _, c := range p.Changes {
+		if c.Destructive && !c.Skipped {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrationPlan : the dry run result of `Migrate`, nothing is executed
+type MigrationPlan struct {
+	Tables []TablePlan
+}
+
+// IsDestructive : report whether any of the table plan may lose the existing data
+func (p MigrationPlan) IsDestructive() bool {
+	for _, t := range p.Tables {
+		if t.IsDestructive() {
+			return true
+		}
+	}
+	return false
+}
+
+// SQL : the statements which will be executed by `Migrate`, in order
+func (p MigrationPlan) SQL() []string {
+	stmts := make([]string, 0)
+	for _, t := range p.Tables {
+		stmts = append(stmts, t.Statements...)
+	}
+	return stmts
+}
+
+// String :
+func (p MigrationPlan) String() string {
+	return strings.Join(p.SQL(), "\n")
+}
+
+func execPlan(ctx context.Context, c Client, p *TablePlan) error {
+	for _, s := range p.Statements {
+		if err := c.execStmt(ctx, &stmt{statement: bytes.NewBufferString(s)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stale : the existing columns or indexes which is not derived from the model
+func stale(names []string, used dictionary) []string {
+	arr := make([]string, 0)
+	for _, n := range names {
+		if !used.has(n) {
+			arr = append(arr, n)
+		}
+	}
+	return arr
+}
+
+// staleIndexes : the index generated for the indexed column which no longer exists in the model,
+// the index created by `AddIndex`, `AddUniqueIndex` or outside of goloquent is never dropped
+func staleIndexes(table string, names []string, used dictionary, cols []string, indexName func(table, col string) string) []string {
+	idxs := newDictionary(names)
+	arr := make([]string, 0)
+	for _, c := range cols {
+		idx := indexName(table, c)
+		if idxs.has(idx) && !used.has(idx) {
+			arr = append(arr, idx)
+		}
+	}
+	return arr
+}
+
+// getRenames : the existing columns which will be renamed, keyed by the new column name,
+// the column is renamed only when the new column is not exists and the previous column is not used by the model
+func getRenames(columns []Column, existing []ColumnInfo) map[string]string {
+	names, cols := newDictionary(nil), newDictionary(columnNames(existing))
+	for _, c := range columns {
+		names.add(c.Name())
+	}
+	renames := make(map[string]string)
+	for _, c := range columns {
+		old := c.renamedFrom()
+		if old == "" || cols.has(c.Name()) || !cols.has(old) || names.has(old) {
+			continue
+		}
+		renames[c.Name()] = old
+	}
+	return renames
+}
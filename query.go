@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/datastore"
 	"github.com/Oskang09/goloquent/expr"
@@ -79,19 +80,36 @@ type group struct {
 	data    []interface{}
 }
 
+type join struct {
+	kind  string
+	table string
+	alias string
+	left  string
+	op    string
+	right string
+}
+
 type scope struct {
 	table      string
+	alias      string
+	joins      []join
 	distinctOn []string
 	projection []string
 	omits      []string
 	ancestors  []group
 	filters    []Filter
 	orders     []interface{}
+	aggregates []expr.Aggregate
+	groupBy    []string
+	havings    []Filter
+	with       []string
 	limit      int32
 	offset     int32
 	errs       []error
 	noScope    bool
-	lockMode   locked
+	// onlyTrashed : only the soft deleted records, it take precedence over `noScope`
+	onlyTrashed bool
+	lockMode    locked
 }
 
 // Query :
@@ -118,6 +136,21 @@ func (q *Query) clone() *Query {
 	}
 }
 
+// softDeleteScope : exclude the soft deleted records, or only the soft deleted records with `OnlyTrashed`
+func (s scope) softDeleteScope(hasSoftDelete bool) scope {
+	if !hasSoftDelete {
+		return s
+	}
+	f := Filter{field: softDeleteColumn, operator: Equal}
+	if s.onlyTrashed {
+		f.operator = NotEqual
+	} else if s.noScope {
+		return s
+	}
+	s.filters = append(append(make([]Filter, 0, len(s.filters)+1), s.filters...), f)
+	return s
+}
+
 func (q *Query) append(query *Query) *Query {
 	q.scope.projection = append(q.scope.projection, query.scope.projection...)
 	q.scope.filters = append(q.scope.filters, query.scope.filters...)
@@ -188,12 +221,153 @@ func (q *Query) Omit(fields ...string) *Query {
 	return q
 }
 
+// With : eager load the entities referenced by the key fields, the loaded entity will be set into
+// the field which tagged with `goloquent:",ref=<key field>"`, each referenced kind is loaded by a single query
+func (q *Query) With(fields ...string) *Query {
+	q = q.clone()
+	arr := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f := strings.TrimSpace(f)
+		if f == "" {
+			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid `With` value %q", f))
+			return q
+		}
+		arr = append(arr, f)
+	}
+	q.with = append(append(make([]string, 0, len(q.with)+len(arr)), q.with...), arr...)
+	return q
+}
+
+// aggregateFuncs : the aggregate functions are written into the statement as it is,
+// so only the known function is allowed
+var aggregateFuncs = map[string]bool{
+	"COUNT": true,
+	"SUM":   true,
+	"AVG":   true,
+	"MIN":   true,
+	"MAX":   true,
+}
+
+func isValidAggregate(a expr.Aggregate) bool {
+	return aggregateFuncs[strings.ToUpper(a.Func)] && strings.TrimSpace(a.Name) != ""
+}
+
+// SelectAggregate : project the aggregate expressions, such as `expr.Count("*").As("total")`
+func (q *Query) SelectAggregate(aggrs ...expr.Aggregate) *Query {
+	q = q.clone()
+	for _, a := range aggrs {
+		if !isValidAggregate(a) {
+			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid `SelectAggregate` value %v", a))
+			return q
+		}
+	}
+	q.aggregates = append(q.aggregates, aggrs...)
+	return q
+}
+
+// GroupBy :
+func (q *Query) GroupBy(fields ...string) *Query {
+	q = q.clone()
+	arr := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f := strings.TrimSpace(f)
+		if f == "" || f == "*" {
+			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid `GroupBy` value %q", f))
+			return q
+		}
+		arr = append(arr, f)
+	}
+	q.groupBy = append(q.groupBy, arr...)
+	return q
+}
+
+// Having : filter the grouped record, the field can be either field name or `expr.Aggregate`
+func (q *Query) Having(field interface{}, op string, value interface{}) *Query {
+	q = q.clone()
+	optr, err := parseOperator(op, false)
+	if err != nil {
+		q.errs = append(q.errs, err)
+		return q
+	}
+	f := Filter{operator: optr, value: value}
+	switch vi := field.(type) {
+	case string:
+		f.field = strings.TrimSpace(vi)
+	case expr.Aggregate:
+		if !isValidAggregate(vi) {
+			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid `Having` field %v", field))
+			return q
+		}
+		f.aggregate = &vi
+	default:
+		q.errs = append(q.errs, fmt.Errorf("goloquent: invalid `Having` field %v", field))
+		return q
+	}
+	q.havings = append(q.havings, f)
+	return q
+}
+
+// As : alias of the table, use to refer the table in join condition
+func (q *Query) As(alias string) *Query {
+	q = q.clone()
+	q.alias = strings.TrimSpace(alias)
+	return q
+}
+
+// Join : inner join the table, the table can be aliased such as "Merchant AS m", and the
+// condition refer the column with "alias.field", `__key__` is the full key of the entity
+// and `$Parent` is the parent key, e.g. Join("Merchant AS m", "o.Merchant", "=", "m.__key__")
+func (q *Query) Join(table, left, op, right string) *Query {
+	return q.join("INNER JOIN", table, left, op, right)
+}
+
+// LeftJoin : left join the table, see `Join`
+func (q *Query) LeftJoin(table, left, op, right string) *Query {
+	return q.join("LEFT JOIN", table, left, op, right)
+}
+
+func (q *Query) join(kind, table, left, op, right string) *Query {
+	q = q.clone()
+	j := join{kind: kind, left: strings.TrimSpace(left), right: strings.TrimSpace(right)}
+	paths := strings.Fields(table)
+	switch {
+	case len(paths) == 1:
+		j.table = paths[0]
+	case len(paths) == 2:
+		j.table, j.alias = paths[0], paths[1]
+	case len(paths) == 3 && strings.EqualFold(paths[1], "AS"):
+		j.table, j.alias = paths[0], paths[2]
+	default:
+		q.errs = append(q.errs, fmt.Errorf("goloquent: invalid join table %q", table))
+		return q
+	}
+	switch op = strings.TrimSpace(op); op {
+	case "=", "<>", "!=", ">", ">=", "<", "<=":
+		j.op = op
+	default:
+		q.errs = append(q.errs, fmt.Errorf("goloquent: invalid join operator %q", op))
+		return q
+	}
+	if j.left == "" || j.right == "" {
+		q.errs = append(q.errs, fmt.Errorf("goloquent: invalid join condition %q %s %q", left, op, right))
+		return q
+	}
+	q.joins = append(append(make([]join, 0, len(q.joins)+1), q.joins...), j)
+	return q
+}
+
 // Unscoped :
 func (q *Query) Unscoped() *Query {
 	q.noScope = true
 	return q
 }
 
+// OnlyTrashed : only the soft deleted records
+func (q *Query) OnlyTrashed() *Query {
+	q.onlyTrashed = true
+	return q
+}
+
 // Find :
 func (q *Query) Find(ctx context.Context, key *datastore.Key, model interface{}) error {
 	if err := q.getError(); err != nil {
@@ -231,6 +405,16 @@ func (q *Query) Get(ctx context.Context, model interface{}) error {
 	return newBuilder(q).getMulti(ctx, model)
 }
 
+// Iter : iterate the records one by one without buffering the whole result set,
+// the iterator must be closed after used
+func (q *Query) Iter(ctx context.Context, model interface{}) (*StreamIterator, error) {
+	q = q.clone()
+	if err := q.getError(); err != nil {
+		return nil, err
+	}
+	return newBuilder(q).iter(ctx, model)
+}
+
 // Paginate :
 func (q *Query) Paginate(ctx context.Context, p *Pagination, model interface{}) error {
 	if err := q.getError(); err != nil {
@@ -300,7 +484,7 @@ func (q *Query) AnyOfAncestor(ancestors ...*datastore.Key) *Query {
 	return q
 }
 
-func (q *Query) where(field, op string, value interface{}, isJSON bool) *Query {
+func parseOperator(op string, isJSON bool) (operator, error) {
 	op = strings.TrimSpace(strings.ToLower(op))
 	var optr operator
 
@@ -325,22 +509,19 @@ func (q *Query) where(field, op string, value interface{}, isJSON bool) *Query {
 		optr = AnyLike
 	case "like", "$like":
 		if isJSON {
-			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid operator %q for json", op))
-			return q
+			return optr, fmt.Errorf("goloquent: invalid operator %q for json", op)
 		}
 		optr = Like
 	case "nlike", "!like", "$nlike":
 		if isJSON {
-			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid operator %q for json", op))
-			return q
+			return optr, fmt.Errorf("goloquent: invalid operator %q for json", op)
 		}
 		optr = NotLike
 	case "match":
 		optr = MatchAgainst
 	default:
 		if !isJSON {
-			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid operator %q", op))
-			return q
+			return optr, fmt.Errorf("goloquent: invalid operator %q", op)
 		}
 
 		switch op {
@@ -353,10 +534,18 @@ func (q *Query) where(field, op string, value interface{}, isJSON bool) *Query {
 		case "isarray":
 			optr = IsArray
 		default:
-			q.errs = append(q.errs, fmt.Errorf("goloquent: invalid operator %q for json", op))
-			return q
+			return optr, fmt.Errorf("goloquent: invalid operator %q for json", op)
 		}
 	}
+	return optr, nil
+}
+
+func (q *Query) where(field, op string, value interface{}, isJSON bool) *Query {
+	optr, err := parseOperator(op, isJSON)
+	if err != nil {
+		q.errs = append(q.errs, err)
+		return q
+	}
 
 	q.filters = append(q.filters, Filter{
 		field:    field,
@@ -373,6 +562,85 @@ func (q *Query) Where(field string, op string, value interface{}) *Query {
 	return q.where(field, op, value, false)
 }
 
+// OrWhere : OR with the previous filter, `Where(a).OrWhere(b).Where(c)` will become `(a OR b) AND c`
+func (q *Query) OrWhere(field string, op string, value interface{}) *Query {
+	q = q.clone()
+	return q.orWhere(field, op, value, false)
+}
+
+// OrWhereJSON : OR with the previous filter using json filter
+func (q *Query) OrWhereJSON(field, op string, v interface{}) *Query {
+	q = q.clone()
+	return q.orWhere(field, op, v, true)
+}
+
+func (q *Query) orWhere(field, op string, value interface{}, isJSON bool) *Query {
+	n := len(q.filters)
+	q = q.where(field, op, value, isJSON)
+	if n <= 0 || len(q.filters) != n+1 {
+		return q
+	}
+	prev, f := q.filters[n-1], q.filters[n]
+	f.isOr = true
+	group := []Filter{prev, f}
+	if prev.isOrGroup() {
+		group = append(append(make([]Filter, 0, len(prev.group)+1), prev.group...), f)
+	}
+	filters := append(make([]Filter, 0, n), q.filters[:n-1]...)
+	q.filters = append(filters, Filter{group: group})
+	return q
+}
+
+// WhereGroup : group the filters of the callback query with parentheses
+func (q *Query) WhereGroup(cb func(q *Query) *Query) *Query {
+	q = q.clone()
+	gq := cb(newQuery(q.db))
+	if gq == nil {
+		return q
+	}
+	if len(gq.errs) > 0 {
+		q.errs = append(q.errs, gq.errs...)
+		return q
+	}
+	switch len(gq.filters) {
+	case 0:
+	case 1:
+		q.filters = append(q.filters, gq.filters[0])
+	default:
+		q.filters = append(q.filters, Filter{group: gq.filters})
+	}
+	return q
+}
+
+// Or : OR the filters of the queries, `Or(q1, q2)` will become `(q1 filters) OR (q2 filters)`
+func (q *Query) Or(queries ...*Query) *Query {
+	q = q.clone()
+	group := make([]Filter, 0, len(queries))
+	for _, qq := range queries {
+		if qq == nil || len(qq.filters) <= 0 {
+			continue
+		}
+		if len(qq.errs) > 0 {
+			q.errs = append(q.errs, qq.errs...)
+			return q
+		}
+		f := Filter{group: qq.filters}
+		if len(qq.filters) == 1 {
+			f = qq.filters[0]
+		}
+		f.isOr = len(group) > 0
+		group = append(group, f)
+	}
+	switch len(group) {
+	case 0:
+	case 1:
+		q.filters = append(q.filters, group[0])
+	default:
+		q.filters = append(q.filters, Filter{group: group})
+	}
+	return q
+}
+
 // WhereEqual :
 func (q *Query) WhereEqual(field string, v interface{}) *Query {
 	return q.Where(field, "=", v)
@@ -615,7 +883,102 @@ func (q *Query) Flush(ctx context.Context) error {
 	return newBuilder(q).deleteByQuery(ctx)
 }
 
+// Restore : restore the soft deleted records which match the query
+func (q *Query) Restore(ctx context.Context) error {
+	if err := q.getError(); err != nil {
+		return err
+	}
+	if q.table == "" {
+		return fmt.Errorf("goloquent: unable to perform restore without table name")
+	}
+	return newBuilder(q).restoreByQuery(ctx)
+}
+
+// ForceDeleteTrashed : permanently delete the soft deleted records which match the query,
+// only the records which soft deleted longer than `olderThan` are deleted, zero means all
+func (q *Query) ForceDeleteTrashed(ctx context.Context, olderThan time.Duration) error {
+	if err := q.getError(); err != nil {
+		return err
+	}
+	if q.table == "" {
+		return fmt.Errorf("goloquent: unable to perform delete without table name")
+	}
+	if olderThan < 0 {
+		return fmt.Errorf("goloquent: invalid duration %v", olderThan)
+	}
+	return newBuilder(q).forceDeleteTrashed(ctx, olderThan)
+}
+
 // Scan :
 func (q *Query) Scan(ctx context.Context, dest ...interface{}) error {
 	return newBuilder(q).scan(ctx, dest...)
 }
+
+// ScanAll : scan the records into struct, map[string]interface{} or slice of them,
+// it's useful for the query with aggregate projection which cannot load into entity
+func (q *Query) ScanAll(ctx context.Context, dest interface{}) error {
+	if err := q.getError(); err != nil {
+		return err
+	}
+	return newBuilder(q).scanAll(ctx, dest)
+}
+
+// Count : count the records of the query, the model is use to resolve the table and soft delete scope
+func (q *Query) Count(ctx context.Context, model interface{}) (uint, error) {
+	if err := q.getError(); err != nil {
+		return 0, err
+	}
+	var count uint
+	if err := newBuilder(q).aggregate(ctx, model, "COUNT(%s)", "", &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Sum : sum of the field, zero if there is no record
+func (q *Query) Sum(ctx context.Context, model interface{}, field string) (float64, error) {
+	if err := q.getError(); err != nil {
+		return 0, err
+	}
+	var sum float64
+	if err := newBuilder(q).aggregate(ctx, model, "COALESCE(SUM(%s),0)", field, &sum); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+// Avg : average of the field, zero if there is no record
+func (q *Query) Avg(ctx context.Context, model interface{}, field string) (float64, error) {
+	if err := q.getError(); err != nil {
+		return 0, err
+	}
+	var avg float64
+	if err := newBuilder(q).aggregate(ctx, model, "COALESCE(AVG(%s),0)", field, &avg); err != nil {
+		return 0, err
+	}
+	return avg, nil
+}
+
+// Min : scan the minimum value of the field into `dest`, the value will be NULL if there is no record
+func (q *Query) Min(ctx context.Context, model interface{}, field string, dest interface{}) error {
+	if err := q.getError(); err != nil {
+		return err
+	}
+	return newBuilder(q).aggregate(ctx, model, "MIN(%s)", field, dest)
+}
+
+// Max : scan the maximum value of the field into `dest`, the value will be NULL if there is no record
+func (q *Query) Max(ctx context.Context, model interface{}, field string, dest interface{}) error {
+	if err := q.getError(); err != nil {
+		return err
+	}
+	return newBuilder(q).aggregate(ctx, model, "MAX(%s)", field, dest)
+}
+
+// Exists : check whether there is any record match the query
+func (q *Query) Exists(ctx context.Context, model interface{}) (bool, error) {
+	if err := q.getError(); err != nil {
+		return false, err
+	}
+	return newBuilder(q).exists(ctx, model)
+}
@@ -0,0 +1,178 @@
+package goloquent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"cloud.google.com/go/datastore"
+)
+
+var typeOfMultiKey = reflect.TypeOf([]*datastore.Key(nil))
+
+// relationChunkSize : the keys of the relation is loaded by chunk, so the bound parameters
+// never exceed the limit of the database, such as 999 of sqlite
+const relationChunkSize = 500
+
+// relation : the field which populated by the entities of the referenced key field,
+// `*datastore.Key` reference to T or *T and `[]*datastore.Key` reference to []T or []*T
+type relation struct {
+	keyPaths []int
+	isMulti  bool
+	index    int
+	isPtr    bool
+	typeOf   reflect.Type
+}
+
+func (r relation) keys(v reflect.Value) []*datastore.Key {
+	fv := getFieldByIndex(v, r.keyPaths)
+	if r.isMulti {
+		keys, _ := fv.Interface().([]*datastore.Key)
+		return keys
+	}
+	if k, isOk := fv.Interface().(*datastore.Key); isOk && k != nil {
+		return []*datastore.Key{k}
+	}
+	return nil
+}
+
+// elem : convert the loaded entity (pointer) into the element type of the relation field
+func (r relation) elem(v reflect.Value) reflect.Value {
+	if r.isPtr {
+		return v
+	}
+	return v.Elem()
+}
+
+func getRelations(t reflect.Type, name string) ([]relation, error) {
+	codec, err := getStructCodec(reflect.New(t).Interface())
+	if err != nil {
+		return nil, err
+	}
+	kf, err := codec.findField(name)
+	if err != nil || (kf.typeOf != typeOfPtrKey && kf.typeOf != typeOfMultiKey) {
+		return nil, fmt.Errorf("goloquent: entity %v has no key field %q", t, name)
+	}
+
+	isMulti := kf.typeOf == typeOfMultiKey
+	relations := make([]relation, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if newTag(sf).Get("ref") != name {
+			continue
+		}
+		ft := sf.Type
+		if isMulti {
+			if ft.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("goloquent: relation field %q of %q must be slice", sf.Name, name)
+			}
+			ft = ft.Elem()
+		}
+		isPtr := ft.Kind() == reflect.Ptr
+		if isPtr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("goloquent: relation field %q has invalid data type %v", sf.Name, sf.Type)
+		}
+		relations = append(relations, relation{
+			keyPaths: kf.paths,
+			isMulti:  isMulti,
+			index:    i,
+			isPtr:    isPtr,
+			typeOf:   ft,
+		})
+	}
+	if len(relations) <= 0 {
+		return nil, fmt.Errorf("goloquent: entity %v has no relation field which reference %q", t, name)
+	}
+	return relations, nil
+}
+
+// loadWith : eager load the relations of the records, `v` is the slice of struct or pointer of struct
+func (b *builder) loadWith(ctx context.Context, v reflect.Value) error {
+	if len(b.query.with) <= 0 || v.Len() <= 0 {
+		return nil
+	}
+	_, t := checkMultiPtr(v)
+	for _, name := range b.query.with {
+		relations, err := getRelations(t, name)
+		if err != nil {
+			return err
+		}
+		for _, r := range relations {
+			if err := b.loadRelation(ctx, v, r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *builder) loadRelation(ctx context.Context, v reflect.Value, r relation) error {
+	kinds, keys := make([]string, 0), make(map[string][]*datastore.Key)
+	dict := make(map[string]bool)
+	for i := 0; i < v.Len(); i++ {
+		for _, k := range r.keys(reflect.Indirect(v.Index(i))) {
+			if k == nil || dict[stringifyKey(k)] {
+				continue
+			}
+			dict[stringifyKey(k)] = true
+			if _, isOk := keys[k.Kind]; !isOk {
+				kinds = append(kinds, k.Kind)
+			}
+			keys[k.Kind] = append(keys[k.Kind], k)
+		}
+	}
+
+	e, err := newEntity(reflect.New(r.typeOf).Interface())
+	if err != nil {
+		return err
+	}
+	entities := make(map[string]reflect.Value)
+	for _, kind := range kinds {
+		for ks := keys[kind]; len(ks) > 0; {
+			n := relationChunkSize
+			if len(ks) < n {
+				n = len(ks)
+			}
+			q := newQuery(b.db).Where(keyFieldName, "in", ks[:n])
+			q.table = kind
+			ks = ks[n:]
+			vv := reflect.New(reflect.SliceOf(reflect.PtrTo(r.typeOf)))
+			if err := newBuilder(q).getMulti(ctx, vv.Interface()); err != nil {
+				return err
+			}
+			vv = vv.Elem()
+			for j := 0; j < vv.Len(); j++ {
+				fv := getFieldByIndex(vv.Index(j).Elem(), e.field(keyFieldName).paths)
+				if k, isOk := fv.Interface().(*datastore.Key); isOk && k != nil {
+					entities[stringifyKey(k)] = vv.Index(j)
+				}
+			}
+		}
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		rv := reflect.Indirect(v.Index(i))
+		fv := rv.Field(r.index)
+		if !r.isMulti {
+			fv.Set(reflect.Zero(fv.Type()))
+			for _, k := range r.keys(rv) {
+				if ev, isOk := entities[stringifyKey(k)]; isOk {
+					fv.Set(r.elem(ev))
+				}
+			}
+			continue
+		}
+		keys := r.keys(rv)
+		arr := reflect.MakeSlice(fv.Type(), 0, len(keys))
+		for _, k := range keys {
+			if ev, isOk := entities[stringifyKey(k)]; isOk {
+				arr = reflect.Append(arr, r.elem(ev))
+			}
+		}
+		fv.Set(arr)
+	}
+	return nil
+}
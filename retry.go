@@ -0,0 +1,64 @@
+package goloquent
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy : re-run the transaction when it fail with retryable error, such as deadlock and serialization failure
+type RetryPolicy struct {
+	// MaxAttempts : maximum attempts including the first run, no retry if it is less than 2
+	MaxAttempts int
+	// Backoff : delay before the first retry, it is doubled on each subsequent retry
+	Backoff time.Duration
+	// MaxBackoff : upper bound of the delay, no upper bound if it is zero
+	MaxBackoff time.Duration
+	// Jitter : fraction (0 to 1) of the delay which will be randomized
+	Jitter float64
+	// Classifier : report whether the error is retryable, default is classified by the dialect
+	Classifier func(err error) bool
+}
+
+func (p RetryPolicy) isRetryable(d Dialect, err error) bool {
+	if p.Classifier != nil {
+		return p.Classifier(err)
+	}
+	return d.IsRetryable(err)
+}
+
+// delay : backoff of the n-th retry
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d = time.Duration(float64(d) * (1 - j*rand.Float64()))
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package goloquent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for n, d := range []time.Duration{10, 20, 40, 50, 50} {
+		if p.delay(n+1) != d*time.Millisecond {
+			t.Errorf(errUnexpectedResult, "delay")
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.delay(2); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Errorf(errUnexpectedResult, "delay")
+		}
+	}
+
+	deadlock := fmt.Errorf("goloquent: %w", &mysqldriver.MySQLError{Number: 1213})
+	if !p.isRetryable(new(mysql), deadlock) || p.isRetryable(new(mysql), errors.New("error")) {
+		t.Errorf(errUnexpectedResult, "isRetryable")
+	}
+	serialization := fmt.Errorf("goloquent: %w", &pq.Error{Code: "40001"})
+	if !p.isRetryable(new(postgres), serialization) || p.isRetryable(new(postgres), deadlock) {
+		t.Errorf(errUnexpectedResult, "isRetryable")
+	}
+	p.Classifier = func(err error) bool { return false }
+	if p.isRetryable(new(mysql), deadlock) {
+		t.Errorf(errUnexpectedResult, "isRetryable")
+	}
+}
@@ -13,6 +13,7 @@ import (
 )
 
 var (
+	typeOfString         = reflect.TypeOf("")
 	typeOfByte           = reflect.TypeOf([]byte(nil))
 	typeOfTime           = reflect.TypeOf(time.Time{})
 	typeOfPtrKey         = reflect.TypeOf(&datastore.Key{})
@@ -162,7 +163,8 @@ func isBaseType(t reflect.Type) bool {
 	case t == typeOfSoftDelete:
 		return true
 	}
-	return false
+	_, isOk := lookupCodec(t)
+	return isOk
 }
 
 type structScan struct {
@@ -183,6 +185,7 @@ func getStructCodec(it interface{}) (*StructCodec, error) {
 
 	structs := newStructCodec(v)
 	structScans := append(make([]structScan, 0), structScan{[]int{}, []int{}, rt, nil, false, structs})
+	autoFields := make(map[string]bool)
 	for len(structScans) > 0 {
 		first := structScans[0]
 		st := first.typeOf
@@ -201,7 +204,7 @@ func getStructCodec(it interface{}) (*StructCodec, error) {
 			st := newTag(sf)
 
 			switch {
-			case st.isSkip():
+			case st.isSkip(), st.isRelation():
 				continue
 			case st.isPrimaryKey():
 				if sf.Type != typeOfPtrKey {
@@ -216,6 +219,25 @@ func getStructCodec(it interface{}) (*StructCodec, error) {
 			if ft == typeOfSoftDelete {
 				st.name = softDeleteColumn
 			}
+			if opt := st.autoField(); opt != "" {
+				switch {
+				case first.field != nil:
+					return nil, fmt.Errorf("goloquent: %s field %q must be the field of the entity", opt, sf.Name)
+				case autoFields[opt]:
+					return nil, fmt.Errorf("goloquent: struct %v has multiple %s field", rt, opt)
+				}
+				if opt == "version" {
+					switch ft.Kind() {
+					case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+					default:
+						return nil, fmt.Errorf("goloquent: version field %q must be integer, but it's %v", sf.Name, ft)
+					}
+				} else if ft != typeOfTime && ft != reflect.PtrTo(typeOfTime) {
+					return nil, fmt.Errorf("goloquent: %s field %q must be time.Time or *time.Time, but it's %v", opt, sf.Name, ft)
+				}
+				autoFields[opt] = true
+			}
 
 			seq := append(first.sequence, i)
 			k := ft.Kind()
@@ -12,8 +12,6 @@ type tag struct {
 	others  map[string]string
 }
 
-// TODO: Eager loading tag
-
 func newTag(sf reflect.StructField) tag {
 	name := sf.Name
 
@@ -29,11 +27,24 @@ func newTag(sf reflect.StructField) tag {
 		"omitempty": false,
 		"unsigned":  false,
 		"longtext":  false,
+		"version":   false,
+		"createdat": false,
+		"updatedat": false,
 	}
 
 	others := make(map[string]string)
 	paths = paths[1:]
 	for _, k := range paths {
+		// reference field name is case sensitive
+		if strings.HasPrefix(strings.ToLower(k), "ref=") {
+			others["ref"] = strings.TrimSpace(k[len("ref="):])
+			continue
+		}
+		// column name is case sensitive
+		if strings.HasPrefix(strings.ToLower(k), "renamedfrom=") {
+			others["renamedfrom"] = strings.TrimSpace(k[len("renamedfrom="):])
+			continue
+		}
 		k = strings.ToLower(k)
 		if _, isValid := options[k]; isValid {
 			options[k] = true
@@ -66,6 +77,16 @@ func (t tag) isSkip() bool {
 	return t.name == "-"
 }
 
+// isRelation : the field is populated by eager loading the entity of the referenced key field
+func (t tag) isRelation() bool {
+	return t.others["ref"] != ""
+}
+
+// renamedFrom : the previous column name of the field, the column will be renamed instead of added on migration
+func (t tag) renamedFrom() string {
+	return t.others["renamedfrom"]
+}
+
 func (t tag) isFlatten() bool {
 	return t.options["flatten"]
 }
@@ -85,3 +106,31 @@ func (t tag) IsUnsigned() bool {
 func (t tag) IsLongText() bool {
 	return t.options["longtext"]
 }
+
+// isVersion : the integer field is used for optimistic locking, it's increased on `Save` and `Upsert`
+func (t tag) isVersion() bool {
+	return t.options["version"]
+}
+
+// isCreatedAt : the time field is filled when the record is inserted, and it's never updated
+func (t tag) isCreatedAt() bool {
+	return t.options["createdat"]
+}
+
+// isUpdatedAt : the time field is filled whenever the record is written
+func (t tag) isUpdatedAt() bool {
+	return t.options["updatedat"]
+}
+
+// autoField : the option of the field which is maintained by goloquent
+func (t tag) autoField() string {
+	switch {
+	case t.isVersion():
+		return "version"
+	case t.isCreatedAt():
+		return "createdAt"
+	case t.isUpdatedAt():
+		return "updatedAt"
+	}
+	return ""
+}
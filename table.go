@@ -33,11 +33,21 @@ func (t *Table) Migrate(ctx context.Context, model interface{}) error {
 	return newBuilder(t.newQuery()).migrate(ctx, model)
 }
 
+// MigratePlan :
+func (t *Table) MigratePlan(ctx context.Context, model interface{}) (*TablePlan, error) {
+	return newBuilder(t.newQuery()).migratePlan(ctx, model)
+}
+
 // Exists :
 func (t *Table) Exists(ctx context.Context) bool {
 	return t.db.dialect.HasTable(ctx, t.name)
 }
 
+// Describe :
+func (t *Table) Describe(ctx context.Context) (*TableInfo, error) {
+	return newBuilder(t.newQuery()).describe(ctx, t.name)
+}
+
 // DropIfExists :
 func (t *Table) DropIfExists(ctx context.Context) error {
 	return newBuilder(t.newQuery()).dropTableIfExists(ctx, t.name)
@@ -63,6 +73,16 @@ func (t *Table) AddUniqueIndex(ctx context.Context, fields ...string) error {
 	return newBuilder(t.newQuery()).addIndex(ctx, fields, uniqueIdx)
 }
 
+// DropColumn : data of the column will be lost
+func (t *Table) DropColumn(ctx context.Context, cols ...string) error {
+	return newBuilder(t.newQuery()).dropColumn(ctx, cols)
+}
+
+// DropIndex : the index which not exists will be ignored
+func (t *Table) DropIndex(ctx context.Context, idxs ...string) error {
+	return newBuilder(t.newQuery()).dropIndex(ctx, idxs)
+}
+
 // Select :
 func (t *Table) Select(fields ...string) *Query {
 	return t.newQuery().Select(fields...)
@@ -83,6 +103,11 @@ func (t *Table) Unscoped() *Query {
 	return t.newQuery().Unscoped()
 }
 
+// OnlyTrashed :
+func (t *Table) OnlyTrashed() *Query {
+	return t.newQuery().OnlyTrashed()
+}
+
 // Find :
 func (t *Table) Find(ctx context.Context, key *datastore.Key, model interface{}) error {
 	return t.newQuery().Find(ctx, key, model)
@@ -98,6 +123,11 @@ func (t *Table) Get(ctx context.Context, model interface{}) error {
 	return t.newQuery().Get(ctx, model)
 }
 
+// Iter :
+func (t *Table) Iter(ctx context.Context, model interface{}) (*StreamIterator, error) {
+	return t.newQuery().Iter(ctx, model)
+}
+
 // Paginate :
 func (t *Table) Paginate(ctx context.Context, p *Pagination, model interface{}) error {
 	return t.newQuery().Paginate(ctx, p, model)
@@ -178,6 +208,26 @@ func (t *Table) RLock() *Query {
 	return t.newQuery().RLock()
 }
 
+// With :
+func (t *Table) With(fields ...string) *Query {
+	return t.newQuery().With(fields...)
+}
+
+// As :
+func (t *Table) As(alias string) *Query {
+	return t.newQuery().As(alias)
+}
+
+// Join :
+func (t *Table) Join(table, left, op, right string) *Query {
+	return t.newQuery().Join(table, left, op, right)
+}
+
+// LeftJoin :
+func (t *Table) LeftJoin(table, left, op, right string) *Query {
+	return t.newQuery().LeftJoin(table, left, op, right)
+}
+
 // OrderBy :
 func (t *Table) OrderBy(fields ...interface{}) *Query {
 	return t.newQuery().OrderBy(fields...)
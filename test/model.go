@@ -1,8 +1,14 @@
 package test
 
 import (
+	"context"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/datastore"
@@ -56,11 +62,191 @@ type User struct {
 	DeleteDateTime   goloquent.SoftDelete `faker:"-"`
 }
 
+// Score :
+type Score struct {
+	Key    *datastore.Key `goloquent:"__key__"`
+	Group  string
+	Point  *int64
+	Detail struct {
+		Level int
+	} `goloquent:",flatten"`
+	CreatedAt time.Time
+}
+
+// Merchant :
+type Merchant struct {
+	Key  *datastore.Key `goloquent:"__key__"`
+	Name string
+}
+
+// Product :
+type Product struct {
+	Key  *datastore.Key `goloquent:"__key__"`
+	Name string
+}
+
+// Order :
+type Order struct {
+	Key          *datastore.Key `goloquent:"__key__"`
+	Merchant     *datastore.Key
+	Products     []*datastore.Key
+	Amount       int64
+	MerchantInfo *Merchant  `goloquent:",ref=Merchant"`
+	ProductList  []*Product `goloquent:",ref=Products"`
+}
+
 // TempUser :
 type TempUser struct {
 	User
 }
 
+// HookUser : record the invoked hooks
+type HookUser struct {
+	Key     *datastore.Key `goloquent:"__key__"`
+	Name    string
+	Counter int
+	Hooks   []string `goloquent:"-"`
+	Deleted goloquent.SoftDelete
+}
+
+// BeforeCreate :
+func (u *HookUser) BeforeCreate(ctx context.Context, db *goloquent.DB) error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	u.Counter++
+	u.Hooks = append(u.Hooks, "BeforeCreate")
+	return nil
+}
+
+// AfterCreate : the record is visible through the db, which is the transaction if it's created within
+func (u *HookUser) AfterCreate(ctx context.Context, db *goloquent.DB) error {
+	u.Hooks = append(u.Hooks, "AfterCreate")
+	return db.Find(ctx, u.Key, new(HookUser))
+}
+
+// BeforeUpdate :
+func (u *HookUser) BeforeUpdate(ctx context.Context, db *goloquent.DB) error {
+	u.Counter++
+	u.Hooks = append(u.Hooks, "BeforeUpdate")
+	return nil
+}
+
+// AfterUpdate :
+func (u *HookUser) AfterUpdate(ctx context.Context, db *goloquent.DB) error {
+	u.Hooks = append(u.Hooks, "AfterUpdate")
+	return nil
+}
+
+// BeforeDelete :
+func (u *HookUser) BeforeDelete(ctx context.Context, db *goloquent.DB) error {
+	u.Hooks = append(u.Hooks, "BeforeDelete")
+	return nil
+}
+
+// AfterDelete :
+func (u *HookUser) AfterDelete(ctx context.Context, db *goloquent.DB) error {
+	u.Hooks = append(u.Hooks, "AfterDelete")
+	return nil
+}
+
+// AfterFind :
+func (u *HookUser) AfterFind(ctx context.Context, db *goloquent.DB) error {
+	u.Hooks = append(u.Hooks, "AfterFind")
+	return nil
+}
+
+// Money : the amount in cents, it implements `driver.Valuer` and `sql.Scanner`
+type Money int64
+
+// Value :
+func (m Money) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d.%02d", int64(m)/100, int64(m)%100), nil
+}
+
+// Scan :
+func (m *Money) Scan(src interface{}) error {
+	var str string
+	switch vi := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		str = string(vi)
+	case string:
+		str = vi
+	default:
+		return fmt.Errorf("unsupported money %v", src)
+	}
+	paths := strings.SplitN(str, ".", 2)
+	if len(paths) != 2 || len(paths[1]) != 2 {
+		return fmt.Errorf("invalid money %q", str)
+	}
+	n, err := strconv.ParseInt(paths[0]+paths[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	*m = Money(n)
+	return nil
+}
+
+// Color : the color is stored as hex by the registered codec
+type Color struct {
+	R, G, B uint8
+}
+
+type colorCodec struct{}
+
+func (colorCodec) DataType() string {
+	return "varchar(7)"
+}
+
+func (colorCodec) Encode(v reflect.Value) (interface{}, error) {
+	c := v.Interface().(Color)
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), nil
+}
+
+func (colorCodec) Decode(v reflect.Value, b []byte) error {
+	c := Color{}
+	if b != nil {
+		if _, err := fmt.Sscanf(string(b), "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			return err
+		}
+	}
+	v.Set(reflect.ValueOf(c))
+	return nil
+}
+
+// Sealed : the registered codec refuse to encode the locked value
+type Sealed struct {
+	Locked bool
+}
+
+var errSealed = errors.New("sealed value is locked")
+
+type sealedCodec struct{}
+
+func (sealedCodec) DataType() string {
+	return "varchar(10)"
+}
+
+func (sealedCodec) Encode(v reflect.Value) (interface{}, error) {
+	if v.Interface().(Sealed).Locked {
+		return nil, errSealed
+	}
+	return "open", nil
+}
+
+func (sealedCodec) Decode(v reflect.Value, b []byte) error {
+	v.Set(reflect.ValueOf(Sealed{}))
+	return nil
+}
+
+func init() {
+	goloquent.RegisterCodec(reflect.TypeOf(Color{}), colorCodec{})
+	goloquent.RegisterCodec(reflect.TypeOf(Sealed{}), sealedCodec{})
+}
+
 func getFakeUser() *User {
 	u := new(User)
 	faker.FakeData(u)
@@ -1,15 +1,25 @@
 package test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/datastore"
 	"github.com/Oskang09/goloquent"
+	"github.com/Oskang09/goloquent/cli"
 	"github.com/Oskang09/goloquent/db"
+	"github.com/Oskang09/goloquent/expr"
 	_ "github.com/lib/pq"
 )
 
@@ -428,6 +438,488 @@ func TestPostgresJSONIsArray(t *testing.T) {
 	}
 }
 
+func TestPostgresIter(t *testing.T) {
+	users := new([]User)
+	if err := pg.Get(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := pg.NewQuery().Iter(ctx, new(User))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	count := 0
+	for it.Next() {
+		u := new(User)
+		if err := it.Scan(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		if u.Key == nil {
+			t.Fatal(errors.New("iterator should patch the primary key"))
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(*users) {
+		t.Fatal(fmt.Errorf("unexpected iterator record count, expected %d, but get %d", len(*users), count))
+	}
+}
+
+func TestPostgresKeysetPaginate(t *testing.T) {
+	if err := pg.Table("Score").DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.Migrate(ctx, new(Score)); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	scores := make([]*Score, 0)
+	for i := 0; i < 11; i++ {
+		s := &Score{Group: fmt.Sprintf("G%d", i%2), CreatedAt: now.Add(time.Duration(i%3) * time.Hour)}
+		s.Detail.Level = i
+		if i%3 != 0 {
+			point := int64(i % 4)
+			s.Point = &point
+		}
+		scores = append(scores, s)
+	}
+	if err := pg.Create(ctx, &scores); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, orders := range [][]interface{}{
+		{"Group"},
+		{"Group", "-Point"},
+		{"-Point", "CreatedAt"},
+		{"-CreatedAt", "Point", "-Group"},
+	} {
+		// paginate always append the primary key as tiebreaker, following the last order direction
+		tiebreaker := "$Key"
+		if last := orders[len(orders)-1].(string); last[0] == '-' {
+			tiebreaker = "-$Key"
+		}
+		expected := new([]Score)
+		if err := pg.NewQuery().OrderBy(append(orders, tiebreaker)...).Get(ctx, expected); err != nil {
+			t.Fatal(err)
+		}
+
+		keys := make([]string, 0)
+		p := &goloquent.Pagination{Limit: 3}
+		for {
+			records := new([]Score)
+			if err := pg.NewQuery().OrderBy(orders...).Paginate(ctx, p, records); err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range *records {
+				keys = append(keys, r.Key.String())
+			}
+			if p.NextCursor() == "" {
+				break
+			}
+			p.Cursor = p.NextCursor()
+		}
+
+		expectedKeys := make([]string, 0)
+		for _, r := range *expected {
+			expectedKeys = append(expectedKeys, r.Key.String())
+		}
+		if fmt.Sprintf("%v", keys) != fmt.Sprintf("%v", expectedKeys) {
+			t.Fatal(fmt.Errorf("unexpected keyset result with orders %v, expected %v, but get %v", orders, expectedKeys, keys))
+		}
+
+		// walk backward from the last page
+		backward := make([]string, 0)
+		for p.PrevCursor() != "" {
+			p.Cursor = p.PrevCursor()
+			records := new([]Score)
+			if err := pg.NewQuery().OrderBy(orders...).Paginate(ctx, p, records); err != nil {
+				t.Fatal(err)
+			}
+			page := make([]string, 0)
+			for _, r := range *records {
+				page = append(page, r.Key.String())
+			}
+			backward = append(page, backward...)
+		}
+		if n := len(keys) - len(backward); n < 0 || fmt.Sprintf("%v", backward) != fmt.Sprintf("%v", keys[:len(keys)-n]) {
+			t.Fatal(fmt.Errorf("unexpected backward keyset result with orders %v, expected %v, but get %v", orders, keys, backward))
+		}
+	}
+}
+
+func TestPostgresAggregate(t *testing.T) {
+	scores := new([]Score)
+	if err := pg.Get(ctx, scores); err != nil {
+		t.Fatal(err)
+	}
+	var sum, min, max int
+	for i, s := range *scores {
+		sum += s.Detail.Level
+		if i == 0 || s.Detail.Level < min {
+			min = s.Detail.Level
+		}
+		if s.Detail.Level > max {
+			max = s.Detail.Level
+		}
+	}
+
+	count, err := pg.NewQuery().Count(ctx, new(Score))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != uint(len(*scores)) {
+		t.Fatal(fmt.Errorf("unexpected count, expected %d, but get %d", len(*scores), count))
+	}
+	if limited, err := pg.NewQuery().Limit(3).Count(ctx, new(Score)); err != nil || limited != 3 {
+		t.Fatal(fmt.Errorf("unexpected count with limit, expected 3, but get %d, %v", limited, err))
+	}
+	total, err := pg.NewQuery().Sum(ctx, new(Score), "Detail.Level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(total) != sum {
+		t.Fatal(fmt.Errorf("unexpected sum, expected %d, but get %v", sum, total))
+	}
+	avg, err := pg.NewQuery().Avg(ctx, new(Score), "Detail.Level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if avg != float64(sum)/float64(count) {
+		t.Fatal(fmt.Errorf("unexpected avg, expected %v, but get %v", float64(sum)/float64(count), avg))
+	}
+	var minLevel, maxLevel int
+	if err := pg.NewQuery().Min(ctx, new(Score), "Detail.Level", &minLevel); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.NewQuery().Max(ctx, new(Score), "Detail.Level", &maxLevel); err != nil {
+		t.Fatal(err)
+	}
+	if minLevel != min || maxLevel != max {
+		t.Fatal(fmt.Errorf("unexpected min max, expected %d %d, but get %d %d", min, max, minLevel, maxLevel))
+	}
+	if _, err := pg.NewQuery().Sum(ctx, new(Score), "Unknown"); err == nil {
+		t.Fatal(errors.New("unknown field should return error"))
+	}
+
+	isExist, err := pg.NewQuery().WhereEqual("Group", "G1").Exists(ctx, new(Score))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isExist {
+		t.Fatal(errors.New("record should exist"))
+	}
+	isExist, err = pg.NewQuery().WhereEqual("Group", "G2").Exists(ctx, new(Score))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isExist {
+		t.Fatal(errors.New("record shouldn't exist"))
+	}
+}
+
+func TestPostgresGroupBy(t *testing.T) {
+	scores := new([]Score)
+	if err := pg.Get(ctx, scores); err != nil {
+		t.Fatal(err)
+	}
+	counts, sums := make(map[string]int64), make(map[string]int64)
+	for _, s := range *scores {
+		counts[s.Group]++
+		if s.Point != nil {
+			sums[s.Group] += *s.Point
+		}
+	}
+
+	reports := new([]struct {
+		Group string
+		Total int64 `goloquent:"total"`
+		Point int64 `goloquent:"point"`
+	})
+	if err := pg.Table("Score").
+		Select("Group").
+		SelectAggregate(expr.Count("*").As("total"), expr.Sum("Point").As("point")).
+		GroupBy("Group").
+		Having(expr.Count("*"), ">", 0).
+		OrderBy("Group").
+		ScanAll(ctx, reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(*reports) != len(counts) {
+		t.Fatal(fmt.Errorf("unexpected group count, expected %d, but get %d", len(counts), len(*reports)))
+	}
+	for _, r := range *reports {
+		if r.Total != counts[r.Group] || r.Point != sums[r.Group] {
+			t.Fatal(fmt.Errorf("unexpected aggregate result for group %q, %d %d", r.Group, r.Total, r.Point))
+		}
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	if err := pg.Table("Score").
+		Select("Group").
+		SelectAggregate(expr.Count("*").As("total")).
+		GroupBy("Group").
+		Having(expr.Count("*"), ">", counts["G1"]).
+		ScanAll(ctx, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["Group"] != "G0" {
+		t.Fatal(fmt.Errorf("unexpected having result, %v", rows))
+	}
+
+	// only the known aggregate function is allowed, as it's written into the statement
+	injected := expr.Aggregate{Func: "COUNT(*) FROM Score; --", Name: "*"}
+	if err := pg.Table("Score").
+		Select("Group").
+		SelectAggregate(injected).
+		ScanAll(ctx, &rows); err == nil {
+		t.Fatal(errors.New("unknown aggregate function should be rejected"))
+	}
+	if err := pg.Table("Score").
+		Select("Group").
+		GroupBy("Group").
+		Having(injected, ">", 0).
+		ScanAll(ctx, &rows); err == nil {
+		t.Fatal(errors.New("unknown aggregate function of having should be rejected"))
+	}
+}
+
+func TestPostgresOrWhere(t *testing.T) {
+	scores := new([]Score)
+	if err := pg.Get(ctx, scores); err != nil {
+		t.Fatal(err)
+	}
+	expected := 0
+	for _, s := range *scores {
+		if (s.Group == "G0" || s.Point == nil) && s.Detail.Level > 3 {
+			expected++
+		}
+	}
+
+	count := func(q *goloquent.Query) uint {
+		n, err := q.Count(ctx, new(Score))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(pg.NewQuery().
+		WhereEqual("Group", "G0").
+		OrWhere("Point", "=", nil).
+		Where("Detail.Level", ">", 3)); n != uint(expected) {
+		t.Fatal(fmt.Errorf("unexpected OrWhere result, expected %d, but get %d", expected, n))
+	}
+	if n := count(pg.NewQuery().
+		Where("Detail.Level", ">", 3).
+		WhereGroup(func(q *goloquent.Query) *goloquent.Query {
+			return q.WhereEqual("Group", "G0").OrWhere("Point", "=", nil)
+		})); n != uint(expected) {
+		t.Fatal(fmt.Errorf("unexpected WhereGroup result, expected %d, but get %d", expected, n))
+	}
+	if n := count(pg.NewQuery().
+		Where("Detail.Level", ">", 3).
+		Or(
+			pg.NewQuery().WhereEqual("Group", "G0"),
+			pg.NewQuery().WhereNull("Point"),
+		)); n != uint(expected) {
+		t.Fatal(fmt.Errorf("unexpected Or result, expected %d, but get %d", expected, n))
+	}
+
+	users := new([]User)
+	if err := pg.NewQuery().
+		WhereJSONEqual("Address>Line1", "7812, Jalan Section 22").
+		OrWhereJSON("Address>Line1", "=", "unknown").
+		Get(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	if len(*users) <= 0 {
+		t.Fatal(errors.New("json OrWhere should return record"))
+	}
+}
+
+func TestPostgresJoin(t *testing.T) {
+	if err := pg.Migrate(ctx, new(Merchant), new(Order)); err != nil {
+		t.Fatal(err)
+	}
+	merchants := []*Merchant{{Name: "M0"}, {Name: "M1"}, {Name: "M2"}}
+	if err := pg.Create(ctx, &merchants); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range merchants[:2] {
+		orders := []*Order{
+			{Merchant: m.Key, Amount: int64(i+1) * 10},
+			{Merchant: m.Key, Amount: int64(i+1) * 20},
+		}
+		if err := pg.Create(ctx, &orders, m.Key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pg.Create(ctx, &Order{Amount: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	results := new([]struct {
+		Order    Order     `goloquent:"o"`
+		Merchant *Merchant `goloquent:"m"`
+	})
+	if err := pg.Table("Order").As("o").
+		LeftJoin("Merchant AS m", "o.Merchant", "=", "m.__key__").
+		OrderBy("o.Amount").
+		ScanAll(ctx, results); err != nil {
+		t.Fatal(err)
+	}
+	if len(*results) != 5 {
+		t.Fatal(fmt.Errorf("unexpected left join result count, expected 5, but get %d", len(*results)))
+	}
+	if r := (*results)[0]; r.Order.Amount != 5 || r.Merchant != nil {
+		t.Fatal(fmt.Errorf("unexpected unmatched left join result, %v", r))
+	}
+	for _, r := range (*results)[1:] {
+		if r.Merchant == nil || !r.Merchant.Key.Equal(r.Order.Merchant) || r.Order.Key.Parent == nil {
+			t.Fatal(fmt.Errorf("unexpected left join result, %v", r))
+		}
+	}
+
+	orders := new([]Order)
+	if err := pg.NewQuery().As("o").
+		Join("Merchant m", "o.$Parent", "=", "m.__key__").
+		Where("m.Name", "=", "M1").
+		Get(ctx, orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(*orders) != 2 {
+		t.Fatal(fmt.Errorf("unexpected join by parent result count, expected 2, but get %d", len(*orders)))
+	}
+	for _, o := range *orders {
+		if !o.Merchant.Equal(merchants[1].Key) || !o.Key.Parent.Equal(merchants[1].Key) {
+			t.Fatal(fmt.Errorf("unexpected join by parent result, %v", o))
+		}
+	}
+
+	count, err := pg.NewQuery().As("o").
+		Join("Merchant m", "o.Merchant", "=", "m.__key__").
+		Count(ctx, new(Order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatal(fmt.Errorf("unexpected join count, expected 4, but get %d", count))
+	}
+
+	// the reserved column is qualified, so it's not ambiguous with the joined table
+	orders = new([]Order)
+	if err := pg.NewQuery().As("o").
+		Join("Merchant m", "o.Merchant", "=", "m.__key__").
+		Ancestor(merchants[1].Key).
+		Get(ctx, orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(*orders) != 2 {
+		t.Fatal(fmt.Errorf("unexpected join with ancestor result count, expected 2, but get %d", len(*orders)))
+	}
+
+	amounts := make([]int64, 0)
+	p := &goloquent.Pagination{Limit: 1}
+	for {
+		orders := new([]Order)
+		if err := pg.NewQuery().As("o").
+			Join("Merchant m", "o.Merchant", "=", "m.__key__").
+			Ancestor(merchants[1].Key).
+			OrderBy("-o.Amount").
+			Paginate(ctx, p, orders); err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range *orders {
+			amounts = append(amounts, o.Amount)
+		}
+		if p.NextCursor() == "" {
+			break
+		}
+		p.Cursor = p.NextCursor()
+	}
+	if fmt.Sprintf("%v", amounts) != "[40 20]" {
+		t.Fatal(fmt.Errorf("unexpected join paginate result, %v", amounts))
+	}
+}
+
+func TestPostgresWith(t *testing.T) {
+	if err := pg.Migrate(ctx, new(Product)); err != nil {
+		t.Fatal(err)
+	}
+	products := []*Product{{Name: "P0"}, {Name: "P1"}}
+	if err := pg.Create(ctx, &products); err != nil {
+		t.Fatal(err)
+	}
+	merchant := new(Merchant)
+	if err := pg.Where("Name", "=", "M2").First(ctx, merchant); err != nil {
+		t.Fatal(err)
+	}
+	order := &Order{
+		Merchant: merchant.Key,
+		Products: []*datastore.Key{products[1].Key, products[0].Key, products[1].Key},
+		Amount:   100,
+	}
+	if err := pg.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	o := new(Order)
+	if err := pg.NewQuery().With("Merchant", "Products").Find(ctx, order.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.MerchantInfo == nil || !o.MerchantInfo.Key.Equal(merchant.Key) || o.MerchantInfo.Name != "M2" {
+		t.Fatal(fmt.Errorf("unexpected eager loaded merchant, %v", o.MerchantInfo))
+	}
+	if len(o.ProductList) != 3 || o.ProductList[0].Name != "P1" || o.ProductList[1].Name != "P0" {
+		t.Fatal(fmt.Errorf("unexpected eager loaded products, %v", o.ProductList))
+	}
+
+	orders := new([]Order)
+	if err := pg.Table("Order").With("Merchant").OrderBy("Amount").Get(ctx, orders); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range *orders {
+		switch {
+		case o.Merchant == nil && o.MerchantInfo != nil:
+			t.Fatal(fmt.Errorf("unexpected eager loaded merchant without key, %v", o))
+		case o.Merchant != nil && (o.MerchantInfo == nil || !o.MerchantInfo.Key.Equal(o.Merchant)):
+			t.Fatal(fmt.Errorf("unexpected eager loaded merchant, %v", o))
+		case o.ProductList != nil:
+			t.Fatal(errors.New("relation should not be loaded without `With`"))
+		}
+	}
+
+	if err := pg.NewQuery().With("Amount").Get(ctx, orders); err == nil {
+		t.Fatal(errors.New("eager load with non key field should be error"))
+	}
+
+	// the keys is loaded by chunk, so it never exceed the limit of the bound parameters
+	many := make([]*Product, 1200)
+	for i := range many {
+		many[i] = &Product{Name: fmt.Sprintf("bulk-%d", i)}
+	}
+	if err := pg.Create(ctx, &many); err != nil {
+		t.Fatal(err)
+	}
+	bulk := &Order{Amount: 1200}
+	for i := len(many) - 1; i >= 0; i-- {
+		bulk.Products = append(bulk.Products, many[i].Key)
+	}
+	if err := pg.Create(ctx, bulk); err != nil {
+		t.Fatal(err)
+	}
+	o = new(Order)
+	if err := pg.NewQuery().With("Products").Find(ctx, bulk.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.ProductList) != len(many) || o.ProductList[0].Name != "bulk-1199" || o.ProductList[len(many)-1].Name != "bulk-0" {
+		t.Fatal(fmt.Errorf("unexpected eager loaded products of %d keys, %d", len(many), len(o.ProductList)))
+	}
+}
+
 func TestPostgresPaginate(t *testing.T) {
 	users := new([]User)
 
@@ -558,6 +1050,900 @@ func TestPostgresRunInTransaction(t *testing.T) {
 	}); err != nil {
 		t.Fatal(err)
 	}
+
+	// the panic of the callback is propagated after the transaction is rolled back
+	u := getFakeUser()
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal(errors.New("panic of the transaction callback should be propagated"))
+			}
+		}()
+		pg.RunInTransaction(func(txn *goloquent.DB) error {
+			if err := txn.Create(ctx, u); err != nil {
+				return err
+			}
+			panic("rollback")
+		})
+	}()
+	if err := pg.Find(ctx, u.Key, new(User)); !errors.Is(err, goloquent.ErrNoSuchEntity) {
+		t.Fatal(fmt.Errorf("the record of the panicked transaction should be rolled back, %v", err))
+	}
+}
+
+func TestPostgresMigratePlan(t *testing.T) {
+	type planV1 struct {
+		Key  *datastore.Key `goloquent:"__key__"`
+		Name string
+		Note string
+	}
+	type planV2 struct {
+		Key      *datastore.Key `goloquent:"__key__"`
+		Name     int64
+		Merchant *datastore.Key
+	}
+
+	tb := pg.Table("PostgresPlan")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(planV1)); err != nil {
+		t.Fatal(err)
+	}
+	p, err := tb.MigratePlan(ctx, new(planV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(map[goloquent.SchemaAction][]goloquent.SchemaChange)
+	for _, c := range p.Changes {
+		changes[c.Action] = append(changes[c.Action], c)
+	}
+	if c := changes[goloquent.ActionAddColumn]; len(c) != 1 || c[0].Column != "Merchant" {
+		t.Fatal(fmt.Errorf("unexpected added columns, %v", c))
+	}
+	if c := changes[goloquent.ActionAddIndex]; len(c) != 1 || c[0].Index != "PostgresPlan_Merchant_Idx" {
+		t.Fatal(fmt.Errorf("unexpected added indexes, %v", c))
+	}
+	if c := changes[goloquent.ActionModifyColumn]; len(c) != 1 || c[0].From != "varchar(191)" || c[0].To != "bigint" {
+		t.Fatal(fmt.Errorf("unexpected type changes, %v", c))
+	}
+	if c := changes[goloquent.ActionDropColumn]; len(c) != 1 || c[0].Column != "Note" || !c[0].Destructive {
+		t.Fatal(fmt.Errorf("unexpected dropped columns, %v", c))
+	}
+	if !p.IsDestructive() {
+		t.Fatal(errors.New("type change should be destructive"))
+	}
+	if p2, err := tb.MigratePlan(ctx, new(planV2)); err != nil || len(p2.Changes) != len(p.Changes) {
+		t.Fatal(fmt.Errorf("plan shouldn't change the schema, %v, %v", p2, err))
+	}
+}
+
+func TestPostgresMigrateRename(t *testing.T) {
+	type renameV1 struct {
+		Key  *datastore.Key `goloquent:"__key__"`
+		Name string
+		Note string `goloquent:",index"`
+	}
+	type renameV2 struct {
+		Key      *datastore.Key `goloquent:"__key__"`
+		FullName string         `goloquent:",renamedFrom=Name"`
+	}
+
+	tb := pg.Table("PostgresRename")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(renameV1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Create(ctx, &renameV1{
+		Key:  datastore.NameKey("PostgresRename", "r1", nil),
+		Name: "Oskang",
+		Note: "note",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := tb.MigratePlan(ctx, new(renameV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := 0
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case goloquent.ActionRenameColumn:
+			if c.Column != "FullName" || c.RenamedFrom != "Name" {
+				t.Fatal(fmt.Errorf("unexpected rename, %v", c))
+			}
+		case goloquent.ActionDropColumn, goloquent.ActionDropIndex:
+			if !c.Skipped {
+				t.Fatal(fmt.Errorf("drop should be skipped without unsafe, %v", c))
+			}
+			skipped++
+		case goloquent.ActionAddColumn:
+			t.Fatal(fmt.Errorf("renamed column shouldn't be added, %v", c))
+		}
+	}
+	if skipped != 1 {
+		t.Fatal(fmt.Errorf("unexpected plan, %v", plan.Changes))
+	}
+
+	if err := tb.Migrate(ctx, new(renameV2)); err != nil {
+		t.Fatal(err)
+	}
+	v := new(renameV2)
+	if err := tb.First(ctx, v); err != nil {
+		t.Fatal(err)
+	}
+	if v.FullName != "Oskang" {
+		t.Fatal(fmt.Errorf("data should be kept after rename, %q", v.FullName))
+	}
+
+	// the stale column and index only dropped with unsafe
+	unsafe := pg.Unsafe().Table("PostgresRename")
+	plan, err = unsafe.MigratePlan(ctx, new(renameV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.IsDestructive() || len(plan.Statements) != 1 {
+		t.Fatal(fmt.Errorf("unexpected unsafe plan, %v", plan.Statements))
+	}
+	if err := unsafe.Migrate(ctx, new(renameV2)); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err := tb.MigratePlan(ctx, new(renameV2)); err != nil || len(plan.Changes) != 0 {
+		t.Fatal(fmt.Errorf("the schema should be up to date, %v, %v", plan, err))
+	}
+
+	// the index created by AddIndex and AddUniqueIndex is never dropped
+	if err := tb.AddIndex(ctx, "FullName"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.AddUniqueIndex(ctx, "FullName"); err != nil {
+		t.Fatal(err)
+	}
+	if err := unsafe.Migrate(ctx, new(renameV2)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := tb.Describe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := 0
+	for _, idx := range info.Indexes {
+		if idx.Name == "PostgresRename_FullName_idx" || idx.Name == "PostgresRename_FullName_unique" {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Fatal(fmt.Errorf("the index created by AddIndex and AddUniqueIndex should be kept, %v", info.Indexes))
+	}
+
+	if err := tb.Migrate(ctx, new(renameV1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.DropIndex(ctx, "NotExists"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.DropColumn(ctx, "Name", "Note"); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err := tb.MigratePlan(ctx, new(renameV2)); err != nil || len(plan.Changes) != 0 {
+		t.Fatal(fmt.Errorf("the column should be dropped, %v, %v", plan, err))
+	}
+}
+
+func TestPostgresDescribe(t *testing.T) {
+	type describeModel struct {
+		Key  *datastore.Key `goloquent:"__key__"`
+		Name string         `goloquent:",index"`
+		Age  int
+		Note *string
+	}
+
+	tb := pg.Table("PostgresDescribe")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(describeModel)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.AddUniqueIndex(ctx, "Age"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := pg.Describe(ctx, "PostgresDescribe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Columns) != 4 {
+		t.Fatal(fmt.Errorf("unexpected columns, %v", info.Columns))
+	}
+	if c, isOk := info.Column("Name"); !isOk || c.DataType != "varchar(191)" || c.IsNullable || c.Default == nil || c.Encoding == "" {
+		t.Fatal(fmt.Errorf("unexpected column, %v", c))
+	}
+	if c, isOk := info.Column("Note"); !isOk || !c.IsNullable || c.Default != nil {
+		t.Fatal(fmt.Errorf("unexpected nullable column, %v", c))
+	}
+	if idx, isOk := info.Index("PostgresDescribe_Age_unique"); !isOk || !idx.IsUnique {
+		t.Fatal(fmt.Errorf("unexpected unique index, %v", idx))
+	}
+	hasPrimary := false
+	for _, idx := range info.Indexes {
+		if idx.IsPrimary && len(idx.Columns) == 1 && idx.Columns[0] == "$Key" {
+			hasPrimary = true
+		}
+	}
+	if !hasPrimary {
+		t.Fatal(fmt.Errorf("primary key should be described, %v", info.Indexes))
+	}
+
+	if _, err := pg.Describe(ctx, "NotExists"); !errors.Is(err, goloquent.ErrTableNotFound) {
+		t.Fatal(fmt.Errorf("unexpected error of missing table, %v", err))
+	}
+}
+
+func TestPostgresCLI(t *testing.T) {
+	type CLIModel struct {
+		Key  *datastore.Key `goloquent:"__key__"`
+		Name string
+	}
+
+	if err := pg.Table("cli_item").DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.Table("CLIModel").DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"migrations/0001_create_cli_item.up.sql":   "CREATE TABLE cli_item (id INT NOT NULL, name VARCHAR(20), PRIMARY KEY (id));\nCREATE INDEX cli_item_name_idx ON cli_item (name);",
+		"migrations/0001_create_cli_item.down.sql": "DROP TABLE IF EXISTS cli_item;",
+		"config.json": fmt.Sprintf(`{"driver": "postgres", "username": "sianloong", "database": "goloquent", "migration_dir": %q, "migration_table": "cli_migrations"}`, filepath.Join(dir, "migrations")),
+	}
+	if err := os.Mkdir(filepath.Join(dir, "migrations"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		out := new(bytes.Buffer)
+		app := &cli.App{Models: []interface{}{new(CLIModel)}, Stdout: out, Stderr: ioutil.Discard}
+		err := app.Run(ctx, append([]string{"-config", filepath.Join(dir, "config.json")}, args...))
+		return out.String(), err
+	}
+	expect := func(output string, args ...string) {
+		out, err := run(args...)
+		if err != nil {
+			t.Fatal(fmt.Errorf("%v failed, %w", args, err))
+		}
+		if !strings.Contains(out, output) {
+			t.Fatal(fmt.Errorf("output of %v should contain %q, output:\n%s", args, output, out))
+		}
+	}
+
+	expect("applied 0001 create_cli_item", "migrate", "up")
+	expect("no pending migration", "migrate", "up")
+	expect("applied", "status")
+	expect("cli_item", "tables")
+	expect("cli_item_name_idx", "indexes", "cli_item")
+	expect("CLIModel: create_table", "diff")
+	expect("CREATE TABLE", "diff", "-sql")
+	// the stock command has no model, diff should fail instead of reporting nothing
+	noModel := &cli.App{Stdout: ioutil.Discard, Stderr: ioutil.Discard}
+	if err := noModel.Run(ctx, []string{"-config", filepath.Join(dir, "config.json"), "diff"}); err == nil || !strings.Contains(err.Error(), "no model is registered") {
+		t.Fatal(fmt.Errorf("diff without model should fail, but got %v", err))
+	}
+
+	if _, err := run("truncate", "cli_item"); err == nil {
+		t.Fatal(fmt.Errorf("truncate without -force should fail"))
+	}
+	if _, err := run("drop", "-force", "cli_unknown"); err == nil {
+		t.Fatal(fmt.Errorf("drop unknown table should fail"))
+	}
+	if _, err := run("migrate", "sideways"); err == nil {
+		t.Fatal(fmt.Errorf("unknown migrate action should fail"))
+	}
+	expect("truncate cli_item", "truncate", "-force", "cli_item")
+	expect("drop cli_item", "drop", "-force", "cli_item")
+	if out, _ := run("tables"); strings.Contains(out, "cli_item") {
+		t.Fatal(fmt.Errorf("cli_item should be dropped, tables:\n%s", out))
+	}
+	expect("reverted 0001 create_cli_item", "migrate", "down")
+	expect("pending", "status")
+}
+
+func TestPostgresHooks(t *testing.T) {
+	if err := pg.Table("HookUser").DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.Migrate(ctx, new(HookUser)); err != nil {
+		t.Fatal(err)
+	}
+	expect := func(u *HookUser, hooks ...string) {
+		if strings.Join(u.Hooks, ",") != strings.Join(hooks, ",") {
+			t.Fatal(fmt.Errorf("unexpected hooks %v, expected %v", u.Hooks, hooks))
+		}
+		u.Hooks = nil
+	}
+
+	u := &HookUser{Key: datastore.NameKey("HookUser", "hook", nil), Name: "hook"}
+	if err := pg.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	expect(u, "BeforeCreate", "AfterCreate")
+	if err := pg.Save(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	expect(u, "BeforeUpdate", "AfterUpdate")
+
+	found := new(HookUser)
+	if err := pg.Find(ctx, u.Key, found); err != nil {
+		t.Fatal(err)
+	}
+	expect(found, "AfterFind")
+	if found.Counter != 2 {
+		t.Fatal(fmt.Errorf("changes of the hooks should be saved, counter %d", found.Counter))
+	}
+
+	// upsert invoke the update hooks when the record exists
+	upserts := []*HookUser{u, {Key: datastore.NameKey("HookUser", "upsert", nil), Name: "upsert"}}
+	if err := pg.Upsert(ctx, &upserts); err != nil {
+		t.Fatal(err)
+	}
+	expect(upserts[0], "BeforeUpdate", "AfterUpdate")
+	expect(upserts[1], "BeforeCreate", "AfterCreate")
+
+	// hook is invoked with the transaction
+	users := []HookUser{{Key: datastore.NameKey("HookUser", "tx", nil), Name: "tx"}}
+	if err := pg.RunInTransaction(func(txn *goloquent.DB) error {
+		return txn.Create(ctx, &users)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expect(&users[0], "BeforeCreate", "AfterCreate")
+
+	users = nil
+	if err := pg.Table("HookUser").OrderBy("Name").Get(ctx, &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Fatal(fmt.Errorf("unexpected records %d", len(users)))
+	}
+	for i := range users {
+		expect(&users[i], "AfterFind")
+	}
+
+	if err := pg.Delete(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	expect(u, "BeforeDelete", "AfterDelete")
+
+	// error of the hook abort the operation
+	invalid := &HookUser{Key: datastore.NameKey("HookUser", "invalid", nil)}
+	if err := pg.Create(ctx, invalid); err == nil {
+		t.Fatal(fmt.Errorf("create should be aborted by the hook"))
+	}
+	if err := pg.Find(ctx, invalid.Key, new(HookUser)); !errors.Is(err, goloquent.ErrNoSuchEntity) {
+		t.Fatal(fmt.Errorf("record should not be created, %v", err))
+	}
+}
+
+func TestPostgresOnlyTrashed(t *testing.T) {
+	type TrashUser struct {
+		Key     *datastore.Key `goloquent:"__key__"`
+		Name    string
+		Deleted goloquent.SoftDelete
+	}
+
+	tb := pg.Table("TrashUser")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(TrashUser)); err != nil {
+		t.Fatal(err)
+	}
+	p1, p2 := datastore.NameKey("Parent", "p1", nil), datastore.NameKey("Parent", "p2", nil)
+	users := []*TrashUser{
+		{Key: datastore.NameKey("TrashUser", "a", p1), Name: "a"},
+		{Key: datastore.NameKey("TrashUser", "b", p1), Name: "b"},
+		{Key: datastore.NameKey("TrashUser", "c", p2), Name: "c"},
+	}
+	if err := tb.Create(ctx, &users); err != nil {
+		t.Fatal(err)
+	}
+	a, c := users[0], users[2]
+	if err := pg.Delete(ctx, &[]*TrashUser{a, c}); err != nil {
+		t.Fatal(err)
+	}
+	count := func(q *goloquent.Query, expected uint) {
+		n, err := q.Count(ctx, new(TrashUser))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expected {
+			t.Fatal(fmt.Errorf("unexpected count %d, expected %d", n, expected))
+		}
+	}
+
+	count(tb.OrderBy("Name"), 1)
+	count(tb.OnlyTrashed(), 2)
+	count(tb.Unscoped(), 3)
+	count(tb.OnlyTrashed().Ancestor(p1), 1)
+	trashed := make([]TrashUser, 0)
+	if err := tb.OnlyTrashed().OrderBy("Name").Get(ctx, &trashed); err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 2 || trashed[0].Name != "a" || trashed[1].Name != "c" || trashed[0].Deleted == nil {
+		t.Fatal(fmt.Errorf("unexpected trashed records, %v", trashed))
+	}
+
+	if err := pg.Restore(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Deleted != nil {
+		t.Fatal(fmt.Errorf("soft delete field should be reset after restore"))
+	}
+	count(tb.OrderBy("Name"), 2)
+
+	if err := pg.Delete(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Where("Name", "=", "a").Restore(ctx); err != nil {
+		t.Fatal(err)
+	}
+	count(tb.OrderBy("Name"), 2)
+	count(tb.OnlyTrashed(), 1)
+
+	// the record is just deleted, it's not older than an hour
+	if err := tb.OnlyTrashed().ForceDeleteTrashed(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	count(tb.Unscoped(), 3)
+	if err := tb.Ancestor(p1).ForceDeleteTrashed(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	count(tb.Unscoped(), 3)
+	if err := tb.OnlyTrashed().ForceDeleteTrashed(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	count(tb.Unscoped(), 2)
+	count(tb.OnlyTrashed(), 0)
+
+	if err := pg.Restore(ctx, &Merchant{Key: datastore.NameKey("Merchant", "restore", nil)}); err == nil {
+		t.Fatal(fmt.Errorf("restore should fail without soft delete field"))
+	}
+}
+
+func TestPostgresCodec(t *testing.T) {
+	type PricedItem struct {
+		Key      *datastore.Key `goloquent:"__key__"`
+		Name     string
+		Price    Money
+		Discount *Money
+		Prices   []Money
+		Color    Color
+		Palette  []Color
+	}
+
+	tb := pg.Table("PricedItem")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(PricedItem)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := pg.Describe(ctx, "PricedItem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range info.Columns {
+		if c.Name == "Color" && c.DataType != "varchar(7)" {
+			t.Fatal(fmt.Errorf("unexpected data type %q of codec", c.DataType))
+		}
+	}
+
+	discount := Money(150)
+	p := &PricedItem{
+		Key:      datastore.NameKey("PricedItem", "p1", nil),
+		Name:     "Apple",
+		Price:    Money(1250),
+		Discount: &discount,
+		Prices:   []Money{100, 2005},
+		Color:    Color{255, 0, 16},
+		Palette:  []Color{{1, 2, 3}, {255, 255, 255}},
+	}
+	p2 := &PricedItem{Key: datastore.NameKey("PricedItem", "p2", nil), Name: "Orange", Price: Money(99)}
+	if err := tb.Create(ctx, &[]*PricedItem{p, p2}); err != nil {
+		t.Fatal(err)
+	}
+
+	o := new(PricedItem)
+	if err := tb.Find(ctx, p.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Price != p.Price || o.Discount == nil || *o.Discount != discount || o.Color != p.Color ||
+		!reflect.DeepEqual(o.Prices, p.Prices) || !reflect.DeepEqual(o.Palette, p.Palette) {
+		t.Fatal(fmt.Errorf("unexpected codec value, %v", o))
+	}
+	o2 := new(PricedItem)
+	if err := tb.Find(ctx, p2.Key, o2); err != nil {
+		t.Fatal(err)
+	}
+	if o2.Discount != nil || o2.Price != p2.Price {
+		t.Fatal(fmt.Errorf("unexpected codec value, %v", o2))
+	}
+
+	o = new(PricedItem)
+	if err := tb.Where("Price", "=", Money(1250)).Where("Color", "=", Color{255, 0, 16}).First(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Key == nil || o.Name != "Apple" {
+		t.Fatal(fmt.Errorf("unable to filter by codec value"))
+	}
+	n, err := tb.Where("Discount", "=", nil).Count(ctx, new(PricedItem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatal(fmt.Errorf("unexpected count %d, expected 1", n))
+	}
+
+	if err := tb.Where("Name", "=", "Orange").Update(ctx, map[string]interface{}{
+		"Price":    Money(101),
+		"Discount": Money(1),
+		"Color":    Color{0, 128, 0},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Find(ctx, p2.Key, o2); err != nil {
+		t.Fatal(err)
+	}
+	if o2.Price != Money(101) || o2.Discount == nil || *o2.Discount != Money(1) || o2.Color != (Color{0, 128, 0}) {
+		t.Fatal(fmt.Errorf("unexpected codec value after update, %v", o2))
+	}
+
+	type SealedItem struct {
+		Key    *datastore.Key `goloquent:"__key__"`
+		Sealed Sealed
+	}
+	sealed := pg.Table("SealedItem")
+	if err := sealed.Migrate(ctx, new(SealedItem)); err != nil {
+		t.Fatal(err)
+	}
+	s := &SealedItem{Key: datastore.NameKey("SealedItem", "s1", nil), Sealed: Sealed{Locked: true}}
+	if err := sealed.Create(ctx, s); !errors.Is(err, errSealed) {
+		t.Fatal(fmt.Errorf("create should return the encode error of the codec, but got %v", err))
+	}
+	if err := sealed.Upsert(ctx, s); !errors.Is(err, errSealed) {
+		t.Fatal(fmt.Errorf("upsert should return the encode error of the codec, but got %v", err))
+	}
+}
+
+func TestPostgresUpdateExpr(t *testing.T) {
+	type Counter struct {
+		Key    *datastore.Key `goloquent:"__key__"`
+		Name   string
+		Hits   int
+		Point  float64
+		Info   json.RawMessage
+		Tags   []string
+		Scores []int
+	}
+
+	tb := pg.Table("Counter")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(Counter)); err != nil {
+		t.Fatal(err)
+	}
+	c := &Counter{
+		Key:    datastore.NameKey("Counter", "c1", nil),
+		Name:   "c1",
+		Hits:   10,
+		Point:  1.5,
+		Info:   json.RawMessage(`{"nickname":"John","age":18}`),
+		Tags:   []string{"a", "b", "c", "b"},
+		Scores: []int{1, 2, 3},
+	}
+	if err := tb.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	q := tb.Where("Name", "=", "c1")
+	if err := q.Update(ctx, expr.Increment("Hits", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Update(ctx, []expr.Update{
+		expr.Decrement("Point", 0.5),
+		expr.JSONSet("Info", "$.nickname", "Doe"),
+		expr.JSONSet("Info", "$.address", map[string]interface{}{"city": "KL"}),
+		expr.ArrayAppend("Tags", "d"),
+		expr.ArrayAppend("Tags", "e"),
+		expr.ArrayRemove("Scores", 2, 3),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Update(ctx, map[string]interface{}{
+		"Hits": expr.Increment("Hits", 1),
+		"Tags": expr.ArrayRemove("Tags", "b"),
+		"Name": "c2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Where("Name", "=", "c2").Update(ctx, expr.JSONRemove("Info", "$.age")); err != nil {
+		t.Fatal(err)
+	}
+
+	o := new(Counter)
+	if err := tb.Find(ctx, c.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	info := make(map[string]interface{})
+	if err := json.Unmarshal(o.Info, &info); err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "c2" || o.Hits != 16 || o.Point != 1 {
+		t.Fatal(fmt.Errorf("unexpected value after increment, %v", o))
+	}
+	if !reflect.DeepEqual(info, map[string]interface{}{"nickname": "Doe", "address": map[string]interface{}{"city": "KL"}}) {
+		t.Fatal(fmt.Errorf("unexpected json value, %s", o.Info))
+	}
+	if !reflect.DeepEqual(o.Tags, []string{"a", "c", "d", "e"}) || !reflect.DeepEqual(o.Scores, []int{1}) {
+		t.Fatal(fmt.Errorf("unexpected slice value, %v, %v", o.Tags, o.Scores))
+	}
+
+	if err := tb.Update(ctx, map[string]interface{}{"Hits": expr.Increment("Point", 1)}); err == nil {
+		t.Fatal(fmt.Errorf("mismatch column should fail"))
+	}
+	if err := tb.Update(ctx, expr.Increment("Hits", "1")); err == nil {
+		t.Fatal(fmt.Errorf("non numeric increment should fail"))
+	}
+	if err := tb.Update(ctx, expr.JSONSet("Info", "nickname", "x")); err == nil {
+		t.Fatal(fmt.Errorf("invalid json path should fail"))
+	}
+}
+
+func TestPostgresVersion(t *testing.T) {
+	type VersionUser struct {
+		Key     *datastore.Key `goloquent:"__key__"`
+		Name    string
+		Version int64 `goloquent:",version"`
+	}
+
+	tb := pg.Table("VersionUser")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(VersionUser)); err != nil {
+		t.Fatal(err)
+	}
+	u := &VersionUser{Key: datastore.NameKey("VersionUser", "v1", nil), Name: "v1"}
+	if err := tb.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := new(VersionUser), new(VersionUser)
+	if err := tb.Find(ctx, u.Key, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Find(ctx, u.Key, b); err != nil {
+		t.Fatal(err)
+	}
+	a.Name = "a"
+	if err := tb.Save(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 1 {
+		t.Fatal(fmt.Errorf("unexpected version %d, expected 1", a.Version))
+	}
+	b.Name = "b"
+	if err := tb.Save(ctx, b); !errors.Is(err, goloquent.ErrConcurrentModification) {
+		t.Fatal(fmt.Errorf("stale entity should fail with concurrent modification, but got %v", err))
+	}
+	if b.Version != 0 {
+		t.Fatal(fmt.Errorf("version should not be increased when the record is not written"))
+	}
+	o := new(VersionUser)
+	if err := tb.Find(ctx, u.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "a" || o.Version != 1 {
+		t.Fatal(fmt.Errorf("unexpected record, %v", o))
+	}
+
+	missing := &VersionUser{Key: datastore.NameKey("VersionUser", "missing", nil), Name: "missing"}
+	if err := tb.Save(ctx, missing); !errors.Is(err, goloquent.ErrConcurrentModification) {
+		t.Fatal(fmt.Errorf("save missing record should fail with concurrent modification, but got %v", err))
+	}
+
+	u2 := &VersionUser{Key: datastore.NameKey("VersionUser", "v2", nil), Name: "v2"}
+	if err := tb.Upsert(ctx, u2); err != nil {
+		t.Fatal(err)
+	}
+	if u2.Version != 1 {
+		t.Fatal(fmt.Errorf("unexpected version %d after upsert, expected 1", u2.Version))
+	}
+	stale := &VersionUser{Key: u2.Key, Name: "stale"}
+	if err := tb.Upsert(ctx, stale); !errors.Is(err, goloquent.ErrConcurrentModification) {
+		t.Fatal(fmt.Errorf("stale upsert should fail with concurrent modification, but got %v", err))
+	}
+	if stale.Version != 0 {
+		t.Fatal(fmt.Errorf("version should be restored when the record is not written"))
+	}
+	u2.Name = "v2-updated"
+	if err := tb.Upsert(ctx, u2); err != nil {
+		t.Fatal(err)
+	}
+	o = new(VersionUser)
+	if err := tb.Find(ctx, u2.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "v2-updated" || o.Version != 2 || u2.Version != 2 {
+		t.Fatal(fmt.Errorf("unexpected record after upsert, %v", o))
+	}
+
+	u3 := &VersionUser{Key: datastore.NameKey("VersionUser", "v3", nil), Name: "v3"}
+	if err := tb.Upsert(ctx, u3); err != nil {
+		t.Fatal(err)
+	}
+	u2.Name = "batch"
+	batch := []*VersionUser{u2, {Key: u3.Key, Name: "stale"}}
+	if err := tb.Upsert(ctx, &batch); !errors.Is(err, goloquent.ErrConcurrentModification) {
+		t.Fatal(fmt.Errorf("batch upsert with stale entity should fail with concurrent modification, but got %v", err))
+	}
+	if u2.Version != 2 {
+		t.Fatal(fmt.Errorf("version should be restored when the batch is not written"))
+	}
+	o = new(VersionUser)
+	if err := tb.Find(ctx, u2.Key, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "v2-updated" || o.Version != 2 {
+		t.Fatal(fmt.Errorf("fresh entity of the conflicted batch should not be written, %v", o))
+	}
+
+	type InvalidVersion struct {
+		Key     *datastore.Key `goloquent:"__key__"`
+		Version string         `goloquent:",version"`
+	}
+	if err := pg.Table("InvalidVersion").Migrate(ctx, new(InvalidVersion)); err == nil || !strings.Contains(err.Error(), "must be integer") {
+		t.Fatal(fmt.Errorf("non integer version field should fail, but got %v", err))
+	}
+}
+
+func TestPostgresTimestamp(t *testing.T) {
+	type StampUser struct {
+		Key       *datastore.Key `goloquent:"__key__"`
+		Name      string
+		CreatedAt time.Time  `goloquent:",createdAt"`
+		UpdatedAt *time.Time `goloquent:",updatedAt"`
+	}
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pg.SetClock(func() time.Time { return now })
+	defer pg.SetClock(nil)
+	tick := func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	}
+	check := func(k *datastore.Key, createdAt, updatedAt time.Time) {
+		o := new(StampUser)
+		if err := pg.Find(ctx, k, o); err != nil {
+			t.Fatal(err)
+		}
+		if !o.CreatedAt.Equal(createdAt) || o.UpdatedAt == nil || !o.UpdatedAt.Equal(updatedAt) {
+			t.Fatal(fmt.Errorf("unexpected timestamps %v and %v, expected %v and %v", o.CreatedAt, o.UpdatedAt, createdAt, updatedAt))
+		}
+	}
+
+	tb := pg.Table("StampUser")
+	if err := tb.DropIfExists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Migrate(ctx, new(StampUser)); err != nil {
+		t.Fatal(err)
+	}
+	created := now
+	u := &StampUser{Key: datastore.NameKey("StampUser", "s1", nil), Name: "s1"}
+	if err := pg.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if !u.CreatedAt.Equal(created) || u.UpdatedAt == nil || !u.UpdatedAt.Equal(created) {
+		t.Fatal(fmt.Errorf("timestamps should be filled on create, %v", u))
+	}
+	check(u.Key, created, created)
+
+	updated := tick()
+	u.CreatedAt = time.Time{}
+	if err := pg.Save(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if !u.UpdatedAt.Equal(updated) {
+		t.Fatal(fmt.Errorf("updatedAt should be filled on save, %v", u.UpdatedAt))
+	}
+	check(u.Key, created, updated)
+
+	updated = tick()
+	up := &StampUser{Key: u.Key, Name: "upsert"}
+	if err := pg.Upsert(ctx, up); err != nil {
+		t.Fatal(err)
+	}
+	if !up.CreatedAt.Equal(created) {
+		t.Fatal(fmt.Errorf("createdAt of the existing record should be kept on upsert, %v", up.CreatedAt))
+	}
+	check(u.Key, created, updated)
+
+	updated = tick()
+	if err := tb.Where("Name", "=", "upsert").Update(ctx, StampUser{Name: "update"}); err != nil {
+		t.Fatal(err)
+	}
+	check(u.Key, created, updated)
+
+	// the update with map or expression has no model, so the updatedAt isn't filled
+	tick()
+	if err := tb.Where("Name", "=", "update").Update(ctx, map[string]interface{}{"Name": "map"}); err != nil {
+		t.Fatal(err)
+	}
+	check(u.Key, created, updated)
+
+	type StampTrash struct {
+		Key     *datastore.Key `goloquent:"__key__"`
+		Deleted goloquent.SoftDelete
+	}
+	trash := pg.Table("StampTrash")
+	if err := trash.Migrate(ctx, new(StampTrash)); err != nil {
+		t.Fatal(err)
+	}
+	st := &StampTrash{Key: datastore.NameKey("StampTrash", "t1", nil)}
+	if err := pg.Upsert(ctx, st); err != nil {
+		t.Fatal(err)
+	}
+	deleted := tick()
+	if err := pg.Delete(ctx, st); err != nil {
+		t.Fatal(err)
+	}
+	ot := new(StampTrash)
+	if err := trash.OnlyTrashed().First(ctx, ot); err != nil {
+		t.Fatal(err)
+	}
+	if ot.Deleted == nil || !(*ot.Deleted).Equal(deleted) {
+		t.Fatal(fmt.Errorf("soft delete should use the clock, %v", ot.Deleted))
+	}
+	if err := trash.OnlyTrashed().ForceDeleteTrashed(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := trash.OnlyTrashed().Count(ctx, new(StampTrash)); err != nil || n != 1 {
+		t.Fatal(fmt.Errorf("the record trashed within an hour by the clock should be kept, %d, %v", n, err))
+	}
+	tick()
+	tick()
+	if err := trash.OnlyTrashed().ForceDeleteTrashed(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := trash.OnlyTrashed().Count(ctx, new(StampTrash)); err != nil || n != 0 {
+		t.Fatal(fmt.Errorf("the record trashed longer than an hour by the clock should be deleted, %d, %v", n, err))
+	}
+
+	explicit := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	u2 := &StampUser{Key: datastore.NameKey("StampUser", "s2", nil), Name: "s2", CreatedAt: explicit}
+	if err := pg.Upsert(ctx, u2); err != nil {
+		t.Fatal(err)
+	}
+	check(u2.Key, explicit, now)
+
+	type InvalidStamp struct {
+		Key       *datastore.Key `goloquent:"__key__"`
+		CreatedAt string         `goloquent:",createdAt"`
+	}
+	if err := pg.Table("InvalidStamp").Migrate(ctx, new(InvalidStamp)); err == nil || !strings.Contains(err.Error(), "must be time.Time") {
+		t.Fatal(fmt.Errorf("non time createdAt field should fail, but got %v", err))
+	}
 }
 
 func TestPostgresScan(t *testing.T) {
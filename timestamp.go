@@ -0,0 +1,52 @@
+package goloquent
+
+import (
+	"reflect"
+	"time"
+)
+
+// timestamp : the column of the createdAt or updatedAt field
+func (e *entity) timestamp(isCreatedAt bool) (Column, bool) {
+	for _, c := range e.columns {
+		if (isCreatedAt && c.field.isCreatedAt()) || (!isCreatedAt && c.field.isUpdatedAt()) {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+func setTime(v reflect.Value, t time.Time) {
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.ValueOf(&t))
+		return
+	}
+	v.Set(reflect.ValueOf(t))
+}
+
+func isZeroTime(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr {
+		return v.IsNil() || v.Elem().Interface().(time.Time).IsZero()
+	}
+	return v.Interface().(time.Time).IsZero()
+}
+
+// touch : fill the updatedAt field, and the createdAt field if the record is going to be inserted,
+// the createdAt which is set by the user or the hooks is kept
+func touch(e *entity, v reflect.Value, now time.Time, isInsert bool) {
+	if c, isOk := e.timestamp(true); isOk && isInsert {
+		if fv := mustGetField(v, c.field); isZeroTime(fv) {
+			setTime(fv, now)
+		}
+	}
+	if c, isOk := e.timestamp(false); isOk {
+		setTime(mustGetField(v, c.field), now)
+	}
+}
+
+// createdAt : name of the createdAt column, it's only written when the record is inserted
+func (e *entity) createdAt() string {
+	if c, isOk := e.timestamp(true); isOk {
+		return c.Name()
+	}
+	return ""
+}
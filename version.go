@@ -0,0 +1,104 @@
+package goloquent
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// versioning : the version field of the entities, the current version is used to match the record
+type versioning struct {
+	column string
+	fields []reflect.Value
+	prevs  []reflect.Value
+}
+
+// version : the column of the version field, the version field is validated by the struct codec
+func (e *entity) version() (Column, bool) {
+	for _, c := range e.columns {
+		if c.field.isVersion() {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// newVersioning : nil if the entity has no version field
+func newVersioning(e *entity) *versioning {
+	c, isOk := e.version()
+	if !isOk {
+		return nil
+	}
+	v := e.slice.Elem()
+	vs := &versioning{column: c.Name()}
+	for i := 0; i < v.Len(); i++ {
+		fv := mustGetField(v.Index(i), c.field)
+		prev := reflect.New(fv.Type()).Elem()
+		prev.Set(fv)
+		vs.fields = append(vs.fields, fv)
+		vs.prevs = append(vs.prevs, prev)
+	}
+	return vs
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// prev : the version of the entity before it's written
+func (vs *versioning) prev(i int) interface{} {
+	v := vs.prevs[i]
+	if isUnsigned(v.Kind()) {
+		return v.Uint()
+	}
+	return v.Int()
+}
+
+// next : the version of the entity after it's written
+func (vs *versioning) next(i int) interface{} {
+	v := vs.prevs[i]
+	if isUnsigned(v.Kind()) {
+		return v.Uint() + 1
+	}
+	return v.Int() + 1
+}
+
+// incr : set the next version to the entities
+func (vs *versioning) incr() {
+	for i, fv := range vs.fields {
+		if isUnsigned(fv.Kind()) {
+			fv.SetUint(vs.next(i).(uint64))
+		} else {
+			fv.SetInt(vs.next(i).(int64))
+		}
+	}
+}
+
+// restore : set the previous version to the entities
+func (vs *versioning) restore() {
+	for i, fv := range vs.fields {
+		fv.Set(vs.prevs[i])
+	}
+}
+
+// at : the versioning of the i-th entity
+func (vs *versioning) at(i int) *versioning {
+	return &versioning{column: vs.column, fields: vs.fields[i : i+1], prevs: vs.prevs[i : i+1]}
+}
+
+// check : the entity should be written by the statement, the conflicted record is left
+// untouched so no row is affected (mysql reports 2 for the updated record)
+func (vs *versioning) check(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("goloquent: %w", err)
+	}
+	if n < int64(len(vs.fields)) {
+		return ErrConcurrentModification
+	}
+	return nil
+}